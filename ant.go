@@ -1,6 +1,8 @@
-package main
+package acogo
 
 import (
+	"math"
+	"math/rand"
 	"sync"
 )
 
@@ -8,6 +10,14 @@ import (
 type Ant interface {
 	ChooseNext(*Node) (*Edge, bool)
 	MarkPath(*Graph)
+	Path() []int
+	Failed() bool
+}
+
+// LocalUpdater is implemented by Ants which update the pheromone on each edge
+// as they cross it rather than only once they reach the goal.
+type LocalUpdater interface {
+	LocalUpdate(*Edge)
 }
 
 // SimpleAnt is the most basic Ant. It probabilistically chooses a path based on
@@ -22,8 +32,18 @@ type SimpleAnt struct {
 	DepositAmt float64
 	// Source of randomness for making probabilistic path decisions
 	RandomSrc chan chan float64
+	// Private source of randomness for making probabilistic path decisions.
+	// If set, it is used instead of RandomSrc.
+	Rand *rand.Rand
+	// Greatest number of steps the ant may take before giving up on reaching
+	// the goal. If 0, there is no limit.
+	MaxSteps int
+	// If set, OnDone is called when the ant is done moving
+	OnDone func()
 	// WaitGroup for reporting back to the system when the goal has been reached
 	waitGroup *sync.WaitGroup
+	// failed is whether the ant gave up or was stuck before reaching the goal
+	failed bool
 }
 
 // NewSimpleAnt creates a SimpleAnt with the input parameters.
@@ -42,40 +62,92 @@ func NewSimpleAnt(lastNodeId int, depositAmt float64, randSrc chan chan float64,
 // an edge leading to the node the ant just visited unless it is the only edge
 // available on the node.
 func (a *SimpleAnt) ChooseNext(node *Node) (*Edge, bool) {
+	if a.arrive(node) {
+		return nil, true
+	}
+
+	return a.chooseWeighted(node, (*Edge).Pheromone), false
+}
+
+// arrive records the ant arriving at node, returning whether it is done
+// moving. The ant is done once it reaches the goal, in which case it has
+// succeeded. It has failed if it instead reaches a node with no outgoing
+// edges or, if MaxSteps is set, has taken MaxSteps steps without reaching the
+// goal.
+func (a *SimpleAnt) arrive(node *Node) bool {
 	a.StepsTaken = append(a.StepsTaken, node.Id)
 
 	if node.Type == Goal {
-		a.waitGroup.Done()
-		return nil, true
+		a.done()
+		return true
 	}
+	if len(node.OutEdges) == 0 || a.MaxSteps > 0 && len(a.StepsTaken)-1 >= a.MaxSteps {
+		a.failed = true
+		a.done()
+		return true
+	}
+	return false
+}
+
+// done reports that the ant is done moving.
+func (a *SimpleAnt) done() {
+	if a.OnDone != nil {
+		a.OnDone()
+	}
+	a.waitGroup.Done()
+}
 
-	total := a.sumpheromones(node.OutEdges)
+// Failed returns whether the ant gave up or was stuck before reaching the
+// goal.
+func (a *SimpleAnt) Failed() bool {
+	return a.failed
+}
 
-	// use RandomSrc to get random float64
-	randChan := make(chan float64)
-	a.RandomSrc <- randChan
-	choice := <-randChan
+// chooseWeighted probabilistically chooses an outgoing edge of node in
+// proportion to weight. It will not choose an edge leading to the node the
+// ant just visited unless it is the only edge available on the node, and
+// returns nil if node has no outgoing edges.
+func (a *SimpleAnt) chooseWeighted(node *Node, weight func(*Edge) float64) *Edge {
+	if len(node.OutEdges) == 0 {
+		return nil
+	}
+	total := a.sumweights(node.OutEdges, weight)
+	choice := a.random()
 
 	pos := 0.0
 	for _, e := range node.OutEdges {
 		if e.EndNodeId != a.LastNodeId {
-			pos += e.Pheromone()
+			pos += weight(e)
 			if choice <= pos/total {
 				a.LastNodeId = node.Id
-				return e, false
+				return e
 			}
 		}
 	}
 	a.LastNodeId = node.Id
-	return node.OutEdges[len(node.OutEdges)-1], false
+	return node.OutEdges[len(node.OutEdges)-1]
+}
+
+// random uses Rand or RandomSrc to get a random float64 in [0.0, 1.0).
+func (a *SimpleAnt) random() float64 {
+	if a.Rand != nil {
+		return a.Rand.Float64()
+	}
+	randChan := make(chan float64)
+	a.RandomSrc <- randChan
+	return <-randChan
 }
 
 // MarkPath lays down pheromone based on the path the ant took to from home
 // to goal. MarkPath will "unloop" the path meaning that any loops in the
 // original path will be eliminated.
 func (a *SimpleAnt) MarkPath(g *Graph) {
-	unlooped := unloop(a.StepsTaken)
-	g.MarkPath(unlooped, a.DepositAmt)
+	g.MarkPath(a.Path(), a.DepositAmt)
+}
+
+// Path returns the unlooped path the ant took from home to goal.
+func (a *SimpleAnt) Path() []int {
+	return unloop(a.StepsTaken)
 }
 
 // unloop takes the steps taken and eliminates any loops. This is done by always
@@ -102,15 +174,137 @@ func unloop(steps []int) []int {
 	return unlooped
 }
 
-// sumpheromones totals the pheromones present on the slice of edges passed in.
-// sumpheromones does not count pheromones from the edge the ant most recently
-// visited.
-func (a *SimpleAnt) sumpheromones(edges []*Edge) float64 {
+// sumweights totals weight over the slice of edges passed in. sumweights does
+// not count the edge the ant most recently visited.
+func (a *SimpleAnt) sumweights(edges []*Edge, weight func(*Edge) float64) float64 {
 	total := 0.0
 	for _, e := range edges {
 		if e.EndNodeId != a.LastNodeId {
-			total += e.Pheromone()
+			total += weight(e)
 		}
 	}
 	return total
 }
+
+// ASAnt is an Ant System ant. It probabilistically chooses a path in
+// proportion to tau^Alpha * eta^Beta, where tau is the pheromone on an edge
+// and eta, the visibility of the edge, is 1/cost.
+type ASAnt struct {
+	SimpleAnt
+
+	// Relative influence of pheromone on path decisions
+	Alpha float64
+	// Relative influence of edge visibility on path decisions
+	Beta float64
+}
+
+// NewASAnt creates an ASAnt with the input parameters.
+func NewASAnt(lastNodeId int, depositAmt, alpha, beta float64, randSrc chan chan float64, wg *sync.WaitGroup) *ASAnt {
+	return &ASAnt{
+		SimpleAnt: *NewSimpleAnt(lastNodeId, depositAmt, randSrc, wg),
+		Alpha:     alpha,
+		Beta:      beta,
+	}
+}
+
+// ChooseNext probabilistically chooses the next edge in the graph to move down
+// in proportion to the edge's weight. ChooseNext will not choose an edge
+// leading to the node the ant just visited unless it is the only edge
+// available on the node.
+func (a *ASAnt) ChooseNext(node *Node) (*Edge, bool) {
+	if a.arrive(node) {
+		return nil, true
+	}
+
+	return a.chooseWeighted(node, a.weight), false
+}
+
+// weight returns tau^Alpha * eta^Beta for the edge.
+func (a *ASAnt) weight(e *Edge) float64 {
+	return math.Pow(e.Pheromone(), a.Alpha) * math.Pow(1/e.Cost, a.Beta)
+}
+
+// ACSAnt is an Ant Colony System ant. With probability Q0 it exploits the
+// edge with the highest weight, otherwise it chooses an edge the way an ASAnt
+// does. ACSAnts evaporate pheromone on each edge as they cross it, pulling it
+// toward Tau0.
+type ACSAnt struct {
+	ASAnt
+
+	// Probability of choosing the edge with the highest weight
+	Q0 float64
+	// Rate of local pheromone evaporation on each edge crossed
+	Xi float64
+	// Amount of pheromone local evaporation pulls each edge toward
+	Tau0 float64
+	// If set, local evaporation keeps pheromone within Graph.TauMin and
+	// Graph.TauMax
+	Graph *Graph
+}
+
+// NewACSAnt creates an ACSAnt with the input parameters.
+func NewACSAnt(lastNodeId int, depositAmt, alpha, beta, q0, xi, tau0 float64, randSrc chan chan float64, wg *sync.WaitGroup) *ACSAnt {
+	return &ACSAnt{
+		ASAnt: *NewASAnt(lastNodeId, depositAmt, alpha, beta, randSrc, wg),
+		Q0:    q0,
+		Xi:    xi,
+		Tau0:  tau0,
+	}
+}
+
+// ChooseNext chooses the next edge in the graph to move down using the
+// pseudo-random proportional rule. ChooseNext will not choose an edge leading
+// to the node the ant just visited unless it is the only edge available on
+// the node.
+func (a *ACSAnt) ChooseNext(node *Node) (*Edge, bool) {
+	if a.arrive(node) {
+		return nil, true
+	}
+
+	if a.random() < a.Q0 {
+		return a.chooseBest(node), false
+	}
+	return a.chooseWeighted(node, a.weight), false
+}
+
+// chooseBest chooses the outgoing edge of node with the highest weight. Ties
+// are broken randomly. chooseBest will not choose an edge leading to the node
+// the ant just visited unless it is the only edge available on the node,
+// and returns nil if node has no outgoing edges.
+func (a *ACSAnt) chooseBest(node *Node) *Edge {
+	if len(node.OutEdges) == 0 {
+		return nil
+	}
+	best := make([]*Edge, 0, len(node.OutEdges))
+	bestWeight := 0.0
+	for _, e := range node.OutEdges {
+		if e.EndNodeId != a.LastNodeId {
+			switch w := a.weight(e); {
+			case len(best) == 0 || w > bestWeight:
+				best, bestWeight = append(best[:0], e), w
+			case w == bestWeight:
+				best = append(best, e)
+			}
+		}
+	}
+	a.LastNodeId = node.Id
+	switch len(best) {
+	case 0:
+		return node.OutEdges[len(node.OutEdges)-1]
+	case 1:
+		return best[0]
+	}
+	return best[int(a.random()*float64(len(best)))]
+}
+
+// LocalUpdate evaporates pheromone on the edge, setting it to
+// (1-Xi)*pheromone + Xi*Tau0.
+func (a *ACSAnt) LocalUpdate(e *Edge) {
+	e.updatepheromone(func(p float64) float64 {
+		p = (1-a.Xi)*p + a.Xi*a.Tau0
+		if a.Graph != nil {
+			p = a.Graph.bound(p)
+		}
+		return p
+	})
+}
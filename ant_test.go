@@ -1,4 +1,4 @@
-package main
+package acogo
 
 import (
 	"fmt"
@@ -58,7 +58,7 @@ func TestChooseNext(t *testing.T) {
 	}
 }
 
-func TestSumpheromones(t *testing.T) {
+func TestSumweights(t *testing.T) {
 	var wg sync.WaitGroup
 	ant := NewSimpleAnt(1, 1.0, make(chan chan float64), &wg)
 	edges := []*Edge{NewEdge(0, 1), NewEdge(0, 6), NewEdge(0, 3), NewEdge(0, 10)}
@@ -68,7 +68,7 @@ func TestSumpheromones(t *testing.T) {
 	edges[3].pheromone = 8.0
 
 	// should sum all but 0->1 node which matches ant's LastNodeId
-	pheromone := ant.sumpheromones(edges)
+	pheromone := ant.sumweights(edges, (*Edge).Pheromone)
 	expected := 14.0
 
 	if expected != pheromone {
@@ -88,3 +88,101 @@ func testUnloop(input, expected []int, t *testing.T) {
 		t.Error(fmt.Sprintf("Unloop failed: expected %v, got %v", expected, unlooped))
 	}
 }
+
+func TestASAntChooseNext(t *testing.T) {
+	randSource := RandomSource{make(chan chan float64), rand.New(rand.NewSource(time.Now().Unix()))}
+	go randSource.Run()
+
+	var wg sync.WaitGroup
+	ant := NewASAnt(1, 1.0, 1.0, 2.0, randSource.RequestChan, &wg)
+
+	// equal pheromone, so choices depend on (1/cost)^2: 4:1
+	edges := []*Edge{NewEdge(0, 1), NewEdge(0, 6), NewEdge(0, 3)}
+	edges[1].Cost = 1.0
+	edges[2].Cost = 2.0
+
+	node := NewNode(0, []*Edge{}, edges, Path)
+
+	edge1Count := 0
+	for i := 0; i < 1000; i++ {
+		ant.LastNodeId = 1
+		choice, _ := ant.ChooseNext(node)
+		if choice == edges[0] {
+			t.Fatal("should never choose edge0 because that is the LastNodeId")
+		}
+		if choice == edges[1] {
+			edge1Count++
+		}
+	}
+	if edge1Count > 850 || edge1Count < 750 {
+		t.Error(fmt.Sprintf("edge1 count should be between 750 and 850 but was %v\n", edge1Count))
+	}
+}
+
+func TestASAntWeight(t *testing.T) {
+	var wg sync.WaitGroup
+	ant := NewASAnt(1, 1.0, 2.0, 3.0, make(chan chan float64), &wg)
+	e := NewEdge(0, 1)
+	e.pheromone = 3.0
+	e.Cost = 2.0
+
+	weight := ant.weight(e)
+	expected := 9.0 / 8.0
+
+	if expected != weight {
+		t.Error(fmt.Sprintf("weight should be %v, got %v", expected, weight))
+	}
+}
+
+func TestACSAntChooseNext(t *testing.T) {
+	randSource := RandomSource{make(chan chan float64), rand.New(rand.NewSource(time.Now().Unix()))}
+	go randSource.Run()
+
+	var wg sync.WaitGroup
+	ant := NewACSAnt(1, 1.0, 1.0, 2.0, 1.0, 0.1, 10.0, randSource.RequestChan, &wg)
+
+	edges := []*Edge{NewEdge(0, 1), NewEdge(0, 6), NewEdge(0, 3)}
+	edges[0].pheromone = 20.0
+	edges[1].pheromone = 5.0
+	edges[2].pheromone = 6.0
+
+	node := NewNode(0, []*Edge{}, edges, Path)
+
+	// with q0 of 1.0 the ant should always exploit the best edge it may take
+	for i := 0; i < 100; i++ {
+		ant.LastNodeId = 1
+		if choice, _ := ant.ChooseNext(node); choice != edges[2] {
+			t.Fatal(fmt.Sprintf("expected edge2 to be chosen but got %v", choice))
+		}
+	}
+}
+
+func TestACSAntLocalUpdate(t *testing.T) {
+	var wg sync.WaitGroup
+	ant := NewACSAnt(1, 1.0, 1.0, 2.0, 0.9, 0.1, 10.0, make(chan chan float64), &wg)
+	e := NewEdge(0, 1)
+	e.pheromone = 20.0
+
+	ant.LocalUpdate(e)
+	expected := 19.0
+
+	if expected != e.Pheromone() {
+		t.Error(fmt.Sprintf("pheromone should be %v, got %v", expected, e.Pheromone()))
+	}
+}
+
+// TestACSAntLocalUpdateBounds ensures that local updates keep pheromone
+// within the bounds of the ant's graph.
+func TestACSAntLocalUpdateBounds(t *testing.T) {
+	var wg sync.WaitGroup
+	g := NewGraph(3, 0, 8, 0.3)
+	g.SetBounds(5.0, 0)
+	ant := NewACSAnt(1, 1.0, 1.0, 2.0, 0.9, 1.0, 1.0, make(chan chan float64), &wg)
+	ant.Graph = g
+	e := g.Nodes[1].InEdge(0)
+
+	ant.LocalUpdate(e)
+	if p := e.Pheromone(); p != 5.0 {
+		t.Error(fmt.Sprintf("pheromone should be kept at TauMin 5.0, got %v", p))
+	}
+}
@@ -0,0 +1,234 @@
+package acogo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint is the state of a Colony between iterations: the graph with the
+// pheromone on each edge, the colony's parameters, the iteration counter, the
+// state of its source of randomness and the best path found so far. A
+// Colony written with WriteCheckpoint and resumed with Checkpoint.Colony
+// continues where it left off.
+type Checkpoint struct {
+	// Graph with the cost and pheromone on each edge
+	Graph JSONGraph `json:"graph"`
+	// Edges of the graph blocked by events, with their cost and pheromone
+	Blocked []JSONEdge `json:"blocked,omitempty"`
+	// Decay factor of the graph and the bounds on pheromone on each edge
+	DecayFactor float64 `json:"decay_factor"`
+	TauMin      float64 `json:"tau_min"`
+	TauMax      float64 `json:"tau_max"`
+	// How pheromone evaporates, as for NewEvaporation
+	Evaporation     string  `json:"evaporation"`
+	EvaporationRate float64 `json:"evaporation_rate"`
+	// How ants deposit pheromone and its parameters, as for NewDeposit
+	Deposit  string  `json:"deposit"`
+	DepositQ float64 `json:"deposit_q"`
+	Elite    float64 `json:"elite"`
+	RankAnts int     `json:"rank_ants"`
+
+	// Parameters of the colony, including the seed of its source of
+	// randomness
+	Config Config `json:"config"`
+	// Number of iterations completed and ants which gave up
+	Iteration int `json:"iteration"`
+	Failed    int `json:"failed"`
+	// Lowest cost path found so far, as node indexes, and its cost
+	BestPath []int   `json:"best_path"`
+	BestCost float64 `json:"best_cost"`
+	// Number of values drawn from the colony's source of randomness since it
+	// was seeded
+	RandomDraws uint64 `json:"random_draws"`
+	// State of the mmas global pheromone update
+	MMASBestPath []int   `json:"mmas_best_path,omitempty"`
+	MMASBestCost float64 `json:"mmas_best_cost,omitempty"`
+	MMASStagnant int     `json:"mmas_stagnant,omitempty"`
+}
+
+// Checkpoint returns the current state of the colony. It must not be called
+// while an iteration is running, but may be called from OnIteration.
+// Checkpoint returns an error if the graph's evaporation or the colony's
+// deposit is not one created by NewEvaporation or NewDeposit.
+func (c *Colony) Checkpoint() (*Checkpoint, error) {
+	g := c.Graph
+	cp := &Checkpoint{
+		Graph:       ToJSON(g),
+		DecayFactor: g.DecayFactor,
+		TauMin:      g.TauMin,
+		TauMax:      g.TauMax,
+		Config:      c.Config,
+		Iteration:   c.Iteration,
+		Failed:      c.Failed,
+		BestPath:    c.Best.Path,
+		BestCost:    c.Best.Cost,
+		RandomDraws: c.source.draws,
+	}
+	for _, e := range g.BlockedEdges() {
+		cp.Blocked = append(cp.Blocked, jsonEdge(g, e))
+	}
+
+	switch ev := g.Evaporation.(type) {
+	case SubtractiveEvaporation:
+		cp.Evaporation, cp.EvaporationRate = "subtractive", ev.Amount
+	case MultiplicativeEvaporation:
+		cp.Evaporation, cp.EvaporationRate = "multiplicative", ev.Rho
+	case VisitedEvaporation:
+		m, ok := ev.Evaporation.(MultiplicativeEvaporation)
+		if !ok {
+			return nil, fmt.Errorf("cannot checkpoint visited evaporation using %T", ev.Evaporation)
+		}
+		cp.Evaporation, cp.EvaporationRate = "visited", m.Rho
+	default:
+		return nil, fmt.Errorf("cannot checkpoint evaporation %T", g.Evaporation)
+	}
+
+	switch d := c.Config.Deposit.(type) {
+	case FixedDeposit:
+		cp.Deposit = "fixed"
+	case QualityDeposit:
+		cp.Deposit, cp.DepositQ = "quality", d.Q
+	case *RankDeposit:
+		cp.Deposit, cp.DepositQ, cp.RankAnts = "rank", d.Q, d.W
+	case *ElitistDeposit:
+		cp.Deposit, cp.DepositQ, cp.Elite = "elitist", d.Q, d.E
+	default:
+		return nil, fmt.Errorf("cannot checkpoint deposit %T", c.Config.Deposit)
+	}
+
+	if c.mmas != nil {
+		cp.MMASBestPath, cp.MMASBestCost, cp.MMASStagnant = c.mmas.BestPath, c.mmas.BestCost, c.mmas.stagnant
+	}
+	return cp, nil
+}
+
+// WriteCheckpoint writes the current state of the colony to w as JSON, as
+// returned by Checkpoint.
+func (c *Colony) WriteCheckpoint(w io.Writer) error {
+	cp, err := c.Checkpoint()
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(cp)
+}
+
+// WriteCheckpointFile writes the current state of the colony to the file at
+// path. The checkpoint is written to a temporary file which then replaces
+// path, so an earlier checkpoint is not lost if writing fails.
+func (c *Colony) WriteCheckpointFile(path string) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if err = c.WriteCheckpoint(f); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// ReadCheckpointFile reads a checkpoint written by WriteCheckpointFile.
+func ReadCheckpointFile(path string) (*Checkpoint, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ReadCheckpoint(buf)
+}
+
+// ReadCheckpoint parses a checkpoint written by WriteCheckpoint.
+func ReadCheckpoint(buf []byte) (*Checkpoint, error) {
+	var cp Checkpoint
+	if err := json.Unmarshal(buf, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// Colony creates a Colony in the state recorded by the checkpoint. Its source
+// of randomness is restored by drawing as many values as the checkpointed
+// colony had drawn, so a deterministic colony resumed from a checkpoint
+// produces the same results as one which was never interrupted.
+func (cp *Checkpoint) Colony() (*Colony, error) {
+	g, err := cp.Graph.graph(cp.DecayFactor)
+	if err != nil {
+		return nil, err
+	}
+	if g.HomeIdx == -1 || g.GoalIdx == -1 {
+		return nil, errors.New("checkpoint graph has no home or goal node")
+	}
+	g.TauMin, g.TauMax = cp.TauMin, cp.TauMax
+	// pheromone is bounded by the default TauMin as the graph is read and
+	// edges without pheromone are read with InitialPheromone, so restore
+	// each edge's pheromone exactly
+	ids := make(map[string]int, len(g.Nodes))
+	for _, n := range g.Nodes {
+		ids[n.Name] = n.Id
+	}
+	for _, je := range cp.Graph.Edges {
+		if e := g.Nodes[ids[je.To]].InEdge(ids[je.From]); e != nil {
+			e.pheromone = je.Pheromone
+		}
+	}
+	for _, je := range cp.Blocked {
+		from, ok := ids[je.From]
+		to, ok2 := ids[je.To]
+		if !ok || !ok2 || g.Nodes[to].InEdge(from) != nil {
+			return nil, fmt.Errorf("checkpoint has invalid blocked edge %v -> %v", je.From, je.To)
+		}
+		if err := je.check(); err != nil {
+			return nil, err
+		}
+		e := newJSONEdge(from, to, je)
+		if je.Pheromone == 0 {
+			e.pheromone = 0
+		}
+		g.addBlocked(e)
+	}
+	for _, path := range [][]int{cp.BestPath, cp.MMASBestPath} {
+		for _, id := range path {
+			if id < 0 || id >= len(g.Nodes) {
+				return nil, fmt.Errorf("checkpoint path has node %d out of range, graph has nodes 0 to %d", id, len(g.Nodes)-1)
+			}
+		}
+	}
+
+	if g.Evaporation, err = NewEvaporation(cp.Evaporation, cp.EvaporationRate); err != nil {
+		return nil, err
+	}
+	best := Best{cp.BestPath, cp.BestCost}
+	cfg := cp.Config
+	if cfg.Deposit, err = NewDeposit(cp.Deposit, cp.DepositQ, cp.Elite, cp.RankAnts); err != nil {
+		return nil, err
+	}
+	// the best path found by rank and elitist deposits is the colony's
+	switch d := cfg.Deposit.(type) {
+	case *RankDeposit:
+		d.Best = best
+	case *ElitistDeposit:
+		d.Best = best
+	}
+
+	c, err := NewColony(g, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.Iteration, c.Failed, c.Best = cp.Iteration, cp.Failed, best
+	c.source.skip(cp.RandomDraws)
+	if c.mmas != nil {
+		c.mmas.BestPath, c.mmas.BestCost, c.mmas.stagnant = cp.MMASBestPath, cp.MMASBestCost, cp.MMASStagnant
+	}
+	return c, nil
+}
@@ -0,0 +1,115 @@
+package acogo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// TestCheckpointResume ensures that a deterministic colony resumed from a
+// checkpoint ends with the same pheromone and best path as a colony which
+// ran without stopping.
+func TestCheckpointResume(t *testing.T) {
+	tests := []struct {
+		ant, deposit, evaporation string
+	}{
+		{AntSimple, "fixed", "subtractive"},
+		{AntAS, "elitist", "multiplicative"},
+		{AntAS, "rank", "visited"},
+		{AntACS, "fixed", "subtractive"},
+		{AntMMAS, "fixed", "multiplicative"},
+	}
+	for _, test := range tests {
+		newColony := func() *Colony {
+			cfg := DefaultConfig()
+			cfg.Ant, cfg.Iterations, cfg.Seed, cfg.Deterministic = test.ant, 20, 7, true
+			cfg.Stagnation = 5
+			var err error
+			if cfg.Deposit, err = NewDeposit(test.deposit, cfg.DepositAmt, 5.0, 6); err != nil {
+				t.Fatal(err)
+			}
+			g := NewGraph(4, 0, 15, 0.3)
+			if g.Evaporation, err = NewEvaporation(test.evaporation, 0.3); err != nil {
+				t.Fatal(err)
+			}
+			colony, err := NewColony(g, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return colony
+		}
+
+		whole := newColony()
+		if _, err := whole.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		first := newColony()
+		for i := 0; i < 10; i++ {
+			first.Step()
+		}
+		var buf bytes.Buffer
+		if err := first.WriteCheckpoint(&buf); err != nil {
+			t.Fatal(err)
+		}
+		cp, err := ReadCheckpoint(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resumed, err := cp.Colony()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resumed.Iteration != 10 {
+			t.Error(fmt.Sprintf("%v: expected to resume at iteration 10 but got %v", test, resumed.Iteration))
+		}
+		if _, err := resumed.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(resumed.Best, whole.Best) {
+			t.Error(fmt.Sprintf("%v: expected best path %v but got %v", test, whole.Best, resumed.Best))
+		}
+		if resumed.Failed != whole.Failed {
+			t.Error(fmt.Sprintf("%v: expected %v failed ants but got %v", test, whole.Failed, resumed.Failed))
+		}
+		if !reflect.DeepEqual(ToJSON(resumed.Graph), ToJSON(whole.Graph)) {
+			t.Error(fmt.Sprintf("%v: expected the same pheromone on each edge after resuming", test))
+		}
+	}
+}
+
+// TestCheckpointErrors ensures that colonies whose evaporation or deposit
+// cannot be recreated are not checkpointed and that invalid checkpoints are
+// rejected.
+func TestCheckpointErrors(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.3)
+	g.Evaporation = VisitedEvaporation{SubtractiveEvaporation{0.3}}
+	colony, err := NewColony(g, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := colony.Checkpoint(); err == nil {
+		t.Error("expected error checkpointing visited subtractive evaporation")
+	}
+
+	g.Evaporation = SubtractiveEvaporation{0.3}
+	cp, err := colony.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.BestPath = []int{0, 9}
+	if _, err := cp.Colony(); err == nil {
+		t.Error("expected error for best path outside the graph")
+	}
+	cp.BestPath = nil
+	cp.Deposit = "none"
+	if _, err := cp.Colony(); err == nil {
+		t.Error("expected error for unknown deposit")
+	}
+	if _, err := ReadCheckpoint([]byte("{")); err == nil {
+		t.Error("expected error for invalid json")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2014 Joanna Solmon. All rights reserved.
+// Use of this source code is governed by the MIT License found in the LICENSE file.
+
+package main
+
+// dashboardHTML is the page served by acogo serve. It fetches the graph from
+// /graph, then draws the pheromone on each edge as it streams in from
+// /events, with buttons to pause, resume and step through the colony.
+const dashboardHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>acogo</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+#controls button { margin-right: 0.5em; }
+#status { margin: 0.5em 0; font-family: monospace; }
+canvas { border: 1px solid #D3D3D3; }
+</style>
+</head>
+<body>
+<div id="controls">
+<button id="pause">Pause</button>
+<button id="resume">Resume</button>
+<button id="step">Step</button>
+</div>
+<div id="status">connecting...</div>
+<canvas id="graph" width="640" height="640"></canvas>
+<script>
+var margin = 30, graph, pos = [], pheromone = [], state = null;
+var canvas = document.getElementById("graph"), ctx = canvas.getContext("2d");
+
+["pause", "resume", "step"].forEach(function(action) {
+	document.getElementById(action).onclick = function() {
+		fetch("/" + action, {method: "POST"});
+	};
+});
+
+// layout places nodes at their positions scaled to the canvas, or around a
+// circle if any node has no position
+function layout() {
+	var positioned = graph.nodes.every(function(n) { return n.pos; });
+	var pts = graph.nodes.map(function(n, i) {
+		if (positioned) {
+			return [n.pos[0], -n.pos[1]];
+		}
+		var theta = Math.PI / 2 - 2 * Math.PI * i / graph.nodes.length;
+		return [Math.cos(theta), -Math.sin(theta)];
+	});
+	var minX = Math.min.apply(null, pts.map(function(p) { return p[0]; }));
+	var maxX = Math.max.apply(null, pts.map(function(p) { return p[0]; }));
+	var minY = Math.min.apply(null, pts.map(function(p) { return p[1]; }));
+	var maxY = Math.max.apply(null, pts.map(function(p) { return p[1]; }));
+	var scale = (canvas.width - 2 * margin) / Math.max(maxX - minX, maxY - minY, 1e-9);
+	pos = pts.map(function(p) {
+		return [margin + (p[0] - minX) * scale, margin + (p[1] - minY) * scale];
+	});
+}
+
+function draw() {
+	ctx.clearRect(0, 0, canvas.width, canvas.height);
+	var lo = Math.min.apply(null, pheromone), hi = Math.max.apply(null, pheromone);
+	var best = {};
+	if (state && state.best_path) {
+		for (var i = 1; i < state.best_path.length; i++) {
+			best[state.best_path[i - 1] + "," + state.best_path[i]] = true;
+		}
+	}
+
+	// draw edges with less pheromone first, and the best path on top
+	var order = graph.edges.map(function(e, i) { return i; });
+	order.sort(function(a, b) { return pheromone[a] - pheromone[b]; });
+	order.forEach(function(i) { drawEdge(i, lo, hi, false); });
+	order.forEach(function(i) {
+		var e = graph.edges[i];
+		if (best[e[0] + "," + e[1]]) {
+			drawEdge(i, lo, hi, true);
+		}
+	});
+
+	graph.nodes.forEach(function(n, i) {
+		ctx.beginPath();
+		ctx.arc(pos[i][0], pos[i][1], n.type === "path" ? 6 : 10, 0, 2 * Math.PI);
+		ctx.fillStyle = n.type === "home" ? "#8B0000" : n.type === "goal" ? "#008000" : "#D3D3D3";
+		ctx.fill();
+	});
+
+	if (state) {
+		var s = state.stats;
+		document.getElementById("status").textContent =
+			"iteration " + s.iteration + "  best " + s.global_best_cost.toPrecision(4) +
+			"  mean " + s.mean_cost.toPrecision(4) + "  distinct paths " + s.distinct_paths +
+			"  failed " + s.failed_ants + (state.done ? "  done" : state.paused ? "  paused" : "");
+	}
+}
+
+function drawEdge(i, lo, hi, highlight) {
+	var e = graph.edges[i], a = pos[e[0]], b = pos[e[1]];
+	var v = hi > lo ? (pheromone[i] - lo) / (hi - lo) : 1;
+	// move edges aside so a->b and b->a both show
+	var dx = b[0] - a[0], dy = b[1] - a[1], len = Math.sqrt(dx * dx + dy * dy) || 1;
+	var nx = -dy / len * 2, ny = dx / len * 2;
+	ctx.beginPath();
+	ctx.moveTo(a[0] + nx, a[1] + ny);
+	ctx.lineTo(b[0] + nx, b[1] + ny);
+	ctx.lineWidth = highlight ? 5 : 1 + 5 * v;
+	ctx.strokeStyle = highlight ? "#DC143C" : "rgba(16, 78, 139, " + (0.04 + 0.96 * v) + ")";
+	ctx.stroke();
+}
+
+fetch("/graph").then(function(r) { return r.json(); }).then(function(g) {
+	graph = g;
+	pheromone = graph.edges.map(function() { return 0; });
+	layout();
+	var events = new EventSource("/events");
+	events.onmessage = function(ev) {
+		state = JSON.parse(ev.data);
+		(state.pheromone || []).forEach(function(p) { pheromone[p[0]] = p[1]; });
+		draw();
+	};
+});
+</script>
+</body>
+</html>
+`
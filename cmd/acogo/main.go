@@ -0,0 +1,888 @@
+// Copyright (c) 2014 Joanna Solmon. All rights reserved.
+// Use of this source code is governed by the MIT License found in the LICENSE file.
+
+/*
+Command acogo runs an ant colony over a graph and writes the resulting
+pheromone levels as a DOT language graph. The colony itself is provided by
+package github.com/jsolmon/acogo.
+
+Command line options:
+
+	antcount: The number of ants to create and run each iteration. Default 20.
+	depositamt: The amount of pheromone each ant deposits on their path. Default 1.0.
+	deposit: How ants deposit pheromone, fixed, quality, rank or elitist. Default fixed.
+	elite: The weight of the extra elitist deposit on the best path found so far. Default 5.0.
+	rankants: The weight of the best path found so far in rank deposits. Default 6.
+	iterations: The number of times each ant runs from home to goal. Default 500.
+	decay: The amount that pheromone on each edge decreases after each iteration. Default 0.3.
+	evaporation: How pheromone decreases after each iteration, subtractive, multiplicative or visited. Default subtractive.
+	dimension: The number of nodes on each side of the square graph. Default 6.
+	topology: The lattice or random graph the graph is generated on, square, rect, grid4, hex, torus, triangular, gnp, ba, ws or geometric. Default square.
+	width: The number of nodes in each row of rect, grid4, hex, torus and triangular graphs. Default dimension.
+	height: The number of rows of rect, grid4, hex, torus and triangular graphs. Default dimension.
+	diagonals: Give torus and map nodes edges to diagonally adjacent nodes. Default true.
+	nodes: The number of nodes in gnp, ba, ws and geometric graphs. Default 50.
+	p: The probability that each pair of nodes in a gnp graph is joined. Default 0.1.
+	m: The number of edges each node added to a ba graph brings. Default 2.
+	k: The number of nearest neighbors each node of a ws graph starts joined to. Default 4.
+	rewire: The probability that each edge of a ws graph is rewired. Default 0.1.
+	radius: The distance within which nodes of a geometric graph are joined. Default 1.5.
+	graphseed: The seed for drawing gnp, ba, ws and geometric graphs. Default 1.
+	start: The index of the node from which the ants start. Default 0.
+	goal: The index of the node that ants are trying to reach. Default is the last node.
+	graph: A DOT file to load the graph from instead of generating a square graph.
+	map: An ASCII map file of a grid world to build the graph from instead of generating a square graph.
+	ant: The type of ant to run, simple, as, acs or mmas. Default simple.
+	alpha: The relative influence of pheromone on as, acs and mmas ants' path decisions. Default 1.0.
+	beta: The relative influence of edge cost on as, acs and mmas ants' path decisions. Default 2.0.
+	q0: The probability that acs ants choose the best edge. Default 0.9.
+	xi: The rate at which acs ants evaporate pheromone on edges they cross. Default 0.1.
+	pbest: The probability of mmas ants taking the best path once converged. Default 0.05.
+	mmasbest: Which mmas ant deposits pheromone, iteration or global. Default iteration.
+	stagnation: The number of iterations without a better path before mmas pheromone is reset. Default 50.
+	scale: How pheromone is scaled onto edge colors, linear, log or percentile. Default linear.
+	scalemax: The pheromone drawn in the darkest color by linear and log scales. Default is the most pheromone on any edge.
+	percentile: The percentile of edge pheromone drawn in the darkest color by the percentile scale. Default 95.
+	colormap: The colors edges are drawn in, alpha, blues, heat, viridis, coolwarm or purplegreen. Default alpha.
+	labels: Label each edge with its pheromone. Default false.
+	legend: Draw a legend of the edge color scale. Default true.
+	format: The format of the output, dot, svg or png. Default dot.
+	o: A file to write the output to. Default is stdout.
+	snapshot-every: Write a frame of the graph every this many iterations. Default 0, no frames.
+	snapshot-dir: The directory frames are written to. Default snapshots.
+	tui: Draw a live heat map of pheromone on the graph to stderr while the colony runs. Default false.
+	addr: The address acogo serve and acogo api listen on. Default :8080.
+	maxjobs: The number of jobs acogo api runs at once. Default 4.
+	maxqueued: The number of jobs which may wait to run in acogo api. Default 16.
+	interval: The time acogo serve waits between iterations. Default 100ms.
+	highlight: Draw the consensus path and the best path found in distinct colors. Default true.
+	stats: A file to write per-iteration statistics to.
+	statsformat: The format of the statistics file, csv or json. Default csv.
+	maxsteps: The number of steps after which an ant gives up on reaching the goal. Default 0, no limit.
+	seed: The seed for the source of randomness. Default is the current time.
+	deterministic: Move ants one at a time so runs with the same seed produce the same output. Default false.
+	checkpoint: A file to write the state of the colony to while it runs.
+	checkpointevery: The number of iterations between checkpoints. Default 100.
+	resume: A checkpoint file to resume the colony from.
+	warmstart: A DOT file written by an earlier run to take the initial pheromone on each edge from.
+	events: A file of events, such as blocking edges, to apply to the graph between iterations.
+	convergewindow: The number of iterations the consensus path must stay the same after events for the colony to have re-converged. Default 10.
+
+When run, acogo will create a square graph of size dimension * dimension with each
+node having connections to adjacent nodes above, below, left, right, and on all
+four diagonals. For example, if dimension is 3, the graph look like:
+
+	0 1 2
+	3 4 5
+	6 7 8
+
+With topology set, the graph is generated on another lattice instead, with
+width nodes in each row and height rows. Nodes are numbered row by row from the
+top left, as in the square graph, so start and goal pick nodes the same way.
+
+	rect: A rectangular grid with edges to adjacent nodes on all four diagonals, like
+	      the square graph.
+	grid4: A rectangular grid with edges only to the nodes above, below, left and right.
+	hex: A honeycomb lattice of zigzag rows, each node having edges to the nodes on
+	     either side of it and to one node in the row above or below.
+	torus: A rectangular grid whose edges wrap around from the last column to the first
+	       and the last row to the first, with diagonal edges unless diagonals is
+	       false.
+	triangular: A triangular lattice with every other row offset by half a node, each
+	            node having edges to the nodes on either side of it and the two nearest
+	            nodes in the rows above and below.
+
+Diagonal edges of rect and torus graphs have a cost of sqrt(2) and all other edges
+a cost of 1.0. Nodes are placed at their positions on the lattice, so hex and
+triangular graphs are drawn with edges of equal length.
+
+The gnp, ba, ws and geometric topologies draw a random graph of nodes nodes
+instead, with edges in both directions, using a source of randomness seeded with
+graphseed, so the same graphseed draws the same graph.
+
+	gnp: An Erdős–Rényi graph, in which each pair of nodes is joined with
+	     probability p.
+	ba: A Barabási–Albert preferential attachment graph, which starts as a clique of
+	    m+1 nodes, with each node added after them joined to m earlier nodes chosen
+	    in proportion to the number of edges they have.
+	ws: A Watts–Strogatz small-world graph, which starts as a ring with each node
+	    joined to its k nearest neighbors, with each edge then rewired to a random
+	    node with probability rewire.
+	geometric: A random geometric graph, with nodes placed uniformly at random in a
+	           square with one node per unit of area on average, and nodes within
+	           radius of each other joined.
+
+Edges of geometric graphs cost the distance between their nodes, and all other
+edges a cost of 1.0. Nodes of geometric graphs are placed where they were drawn,
+and nodes of other random graphs around a circle. If the goal cannot be reached
+from the start node, the graph is drawn again, and acogo exits with an error if
+none of 100 graphs drawn connect them.
+
+When run as acogo generate, e.g.
+
+	acogo generate -topology geometric -nodes 200 -graphseed 7 -o bench.dot
+
+acogo writes the graph it would run the colony on in format, without running the
+colony, so a generated graph can be saved and run again with graph. The output
+has no legend.
+
+antcount ants will be placed at the start node on the graph and travel in the graph
+until they reach the goal node. At each node, the ant will probabilitstically chose
+which node to travel to next proportionate to the amount of pheromone on each
+outgoing node. Ants will not return to the node they just left unless that is the
+only option for exiting a particular node.
+
+When ant is as, ants follow the Ant System rule instead, choosing each edge in
+proportion to pheromone^alpha * (1/cost)^beta. Edges have a cost of 1.0, except
+for diagonal edges in the square graph which have a cost of sqrt(2). alpha and beta
+must not be negative.
+
+When ant is acs, ants follow the Ant Colony System rules. With probability q0
+an ant takes the edge with the highest pheromone^alpha * (1/cost)^beta, otherwise it
+chooses an edge the way an as ant does. Each time an ant crosses an edge, the
+pheromone on it is set to (1-xi)*pheromone + xi*tau0. Once all ants reach the goal,
+the pheromone on only the edges of the lowest cost path found so far is set to
+(1-decay)*pheromone + decay*depositamt/cost. tau0 is 0.1 until an ant first reaches
+the goal, when pheromone on every edge is set to tau0 = depositamt/(n*cost) for a
+graph of n nodes, which pheromone on an edge is kept above. q0 and xi must be
+between 0 and 1, and decay greater than 0 and at most 1.
+
+When ant is acs or mmas, pheromone is updated only by their rules, so deposit and
+evaporation cannot be set.
+
+When ant is mmas, ants choose edges the way as ants do, but pheromone is updated
+following the MAX-MIN Ant System. Pheromone on each edge is multiplied by 1-decay,
+and only the ant with the lowest cost path in the iteration (or so far, if mmasbest
+is global) adds depositamt/cost pheromone to its path. Pheromone on each edge is
+kept between bounds derived from the lowest cost path found so far: the upper bound
+is depositamt/(decay*cost), and the lower bound is chosen so that ants take that
+path with probability pbest once the colony has converged. Pheromone on all edges
+is set to the upper bound at the start and whenever no better path has been found
+in stagnation iterations. decay must be greater than 0 and at most 1, and pbest
+between 0 and 1, exclusive.
+
+Once all ants reach the goal node in a given iteration, depositamt pheromone will
+be added to each edge that each ant traveled on. After reaching the goal, ants
+routes are unlooped, so an ant that traveled 1->4->5->2->4->8 would only lay down
+pheromone from 1->4->8. After all ant pheromone has been laid down, decay pheromone
+is subtracted from all edges.
+
+When deposit is quality, each ant instead adds depositamt/cost pheromone, where
+cost is the total cost of its unlooped path. When deposit is elitist, the lowest
+cost path found so far additionally receives elite*depositamt/cost pheromone.
+When deposit is rank, ants are ranked by the cost of their paths and only the
+best rankants-1 ants add pheromone, with the ant ranked r adding
+(rankants-r)*depositamt/cost, and the lowest cost path found so far receives
+rankants*depositamt/cost. elite must not be negative, and rankants must be
+at least 1.
+
+When evaporation is multiplicative, pheromone on each edge is instead multiplied by
+1-decay, so decay is the proportion of pheromone that evaporates. When evaporation
+is visited, pheromone is multiplied by 1-decay only on edges that an ant crossed in
+that iteration. For both, decay must be greater than 0 and at most 1, and for
+subtractive evaporation it must not be negative.
+
+When graph is set, the graph is read from a DOT file instead. The home and goal
+nodes are marked with a role attribute and edges may set their initial pheromone
+and cost, e.g.
+
+	digraph {
+		a [role=home]
+		d [role=goal]
+		a -> b -> d
+		a -> c -> d [pheromone=5.0, cost=2.5]
+	}
+
+The start, goal, dimension and topology options are ignored for graphs read from a file.
+Edges in undirected graphs are added in both directions. pheromone and cost must be
+positive, and pheromone below 0.1 is raised to 0.1.
+
+When map is set, the graph is built from an ASCII map of a grid world instead,
+with each character a cell of the grid and each line a row, e.g.
+
+	H..#....
+	.#.#.##.
+	.#...#.G
+
+A . is a free cell, # a wall, H the home cell, G the goal cell and a digit 1 to 9 a
+free cell which costs the digit to enter. Lines shorter than the longest line are
+filled out with walls. Each free cell has edges to the free cells above, below, left
+and right of it, and unless diagonals is false, to diagonally adjacent free cells
+as long as both cells the diagonal passes between are free, so ants can't cut the
+corners of walls. Each edge costs the cost of the cell it enters, times sqrt(2) for
+diagonal edges. Walls have no edges and are drawn as dark squares. The start, goal,
+dimension and topology options are ignored for maps.
+
+Ants normally travel through the graph concurrently, so runs differ even with the
+same seed. When deterministic is set, each ant instead travels from home to goal in
+turn with its own source of randomness seeded from seed, so runs with the same seed
+produce the same output.
+
+When checkpoint is set, the state of the colony is written to the checkpoint file
+as JSON every checkpointevery iterations and when the colony finishes: the graph
+with the cost and pheromone on each edge, the colony's parameters, the iteration
+counter, the state of the source of randomness and the lowest cost path found so
+far. If acogo is interrupted with Ctrl-C, it stops after the current iteration and
+writes a checkpoint before exiting. Run with resume set to a checkpoint file, acogo
+continues the colony where it left off, ignoring the options which set up the graph
+and the colony except iterations, which if given sets the total number of
+iterations of the resumed colony. A deterministic colony resumed from a checkpoint
+produces the same output as one which was never interrupted.
+
+The DOT output gives each edge pheromone and cost attributes, so it can be read back
+in with graph to continue from the pheromone of an earlier run. To start a new graph
+with the pheromone of an earlier run, set warmstart to its DOT output: each edge
+between nodes with the same names as an edge in the output starts with its
+pheromone, and other edges start with the usual initial pheromone.
+
+When events is set, the events in the events file are applied to the graph between
+iterations, one to a line, e.g.
+
+	# iteration action nodes
+	200 block 14 21
+	200 block 21 14
+	300 unblock 14 21
+	300 unblock 21 14
+	400 cost 5 6 10
+	500 goal 30
+
+Each line gives the number of iterations after which the event is applied, an
+action and the names of the nodes it applies to. block removes the edge from one
+node to another, keeping its pheromone, and unblock restores it. cost sets the cost
+of an edge, and goal moves the goal to another node. After events are applied, the
+lowest cost path found so far is forgotten if it no longer leads from start to goal,
+and acogo exits with an error if the goal can no longer be reached from the start
+node. For each iteration at which events were applied, the number of iterations the
+colony took to re-converge is written to stderr: the colony has re-converged once
+the consensus path reaches the goal and stays the same for convergewindow
+iterations. Blocked edges are kept in checkpoints, and a colony resumed from a
+checkpoint should be given the same events file. events cannot be used with acogo
+serve.
+
+When maxsteps is set, an ant which has taken maxsteps steps without reaching the
+goal gives up and does not lay down any pheromone, as does an ant which reaches a
+node with no edges out of it. The number of ants which gave up is written to
+stderr. acogo exits with an error if start or goal is not a node in the graph, if
+they are the same node, or if the goal cannot be reached from the start node.
+Unless maxsteps is set, it also exits with an error if the goal cannot be reached
+from some node which can be reached from the start node, as ants wandering into
+that node would never arrive.
+
+After the last iteration, the consensus path, found by following the edge with the
+most pheromone out of each node from start, and the lowest cost path any ant took
+are written to stderr. Unless highlight is false, they are drawn in the DOT output
+in orange and red respectively, with red drawn over orange where they overlap.
+
+When tui is set, a heat map of the graph is drawn to stderr with ANSI colors and
+redrawn ten times a second while the colony runs. Each node is drawn as a cell
+colored by the most pheromone on any edge out of it, using scale and colormap, with
+an arrow pointing along that edge. The home node is marked H and the goal node G.
+Below the heat map is the iteration, the cost and number of steps of the lowest
+cost path found so far, the number of ants moving through the graph and the number
+of ants which gave up. tui needs a graph whose nodes lie on a grid, such as the
+square graph or a graph read from a file with a pos attribute on each node.
+
+When run as acogo serve, e.g.
+
+	acogo serve -addr :8080 -ant acs
+
+acogo instead serves a page on addr which draws the graph in the browser and
+updates it live as the colony runs, waiting interval between iterations. The
+colony can be paused, resumed and stepped through one iteration at a time from the
+page. The page receives the pheromone on the edges which changed and the
+statistics for each iteration as Server-Sent Events from /events, and the colony
+is controlled by POST requests to /pause, /resume and /step. acogo serve takes
+the same options as acogo but writes no output. Statistics, snapshots and
+checkpoints are written as usual, with the stats file flushed, the animation
+written and a final checkpoint written once the colony finishes.
+
+When run as acogo api, acogo serves an HTTP JSON API on addr for running colonies
+in the background instead:
+
+	POST /jobs              submit a job, returning its id
+	GET /jobs               list the status of every job
+	GET /jobs/{id}          get the progress, best path and, once finished, the
+	                        consensus path and pheromone on each edge of a job
+	POST /jobs/{id}/cancel  cancel a queued or running job
+	DELETE /jobs/{id}       cancel a job if it has not finished and forget it
+
+A job gives the graph as JSON or DOT, the names of the home and goal nodes unless
+they are marked with roles in the graph, and any colony parameters which differ
+from the defaults, e.g.
+
+	{
+		"graph": {
+			"nodes": [{"name": "a"}, {"name": "d"}],
+			"edges": [{"from": "a", "to": "b"}, {"from": "b", "to": "d"},
+				{"from": "a", "to": "c"}, {"from": "c", "to": "d", "cost": 2.5}]
+		},
+		"home": "a",
+		"goal": "d",
+		"config": {"ant": "acs", "iterations": 200},
+		"evaporation": "multiplicative",
+		"decay": 0.1
+	}
+
+At most maxjobs jobs run at once, with up to maxqueued more waiting for them to
+finish. Jobs submitted beyond that are refused with 503 Service Unavailable. Jobs
+with more than 10000 ants, 1000000 iterations or 100000 nodes, and jobs with nodes
+reachable from home from which the goal cannot be reached, are refused with 400 Bad
+Request, as are jobs with parameters outside the ranges accepted on the command
+line. A job which panics is reported as failed with the error. Finished jobs are
+forgotten after an hour, or once 100 more recent jobs have finished. The other
+command line options are ignored by acogo api.
+
+When stats is set, statistics are written to the stats file after each iteration:
+the lowest and mean cost of the unlooped paths ants took, the lowest cost path found
+so far, the number of distinct paths taken, the number of ants which gave up and
+the total pheromone on the graph. With statsformat csv they are written as CSV with
+a header row, and with statsformat json as one JSON object per line.
+
+When all ants have completed iterations iterations, a DOT language representation
+of the final graph is written to stdout, or to the file o if set. Edge colors reflect how much pheromone
+is on a given edge with darker edges representing more pheromone. With scale linear,
+edges with the least pheromone on the graph are drawn in the first color of colormap
+and edges with the most, or scalemax if set, in the last. With scale log, pheromone
+is scaled logarithmically instead, and with scale percentile, edges at or above the
+given percentile of pheromone are all drawn in the last color. The alpha colormap
+draws edges in blue of increasing opacity, blues, heat and viridis are sequential
+palettes and coolwarm and purplegreen are diverging palettes centered on the middle
+of the scale. Unless legend is false, the output includes a legend of the scale,
+which is ignored if the output is read back in with graph.
+
+Nodes of the square graph are placed 72 points apart in a grid, and nodes of graphs
+read from a file keep their pos attribute. When every node has a position, the
+output pins each node to it and sets the layout to neato, so the graph keeps its
+shape when rendered, e.g. with neato -n -Tpng.
+
+With format svg or png, acogo draws the graph itself instead, so Graphviz is not
+needed. Nodes are drawn at their positions, or around a circle if any node has
+none, and edges are colored as in the DOT output and drawn thicker the more
+pheromone they have. PNG output has no text labels.
+
+When snapshot-every is set, a frame of the graph is written to snapshot-dir every
+snapshot-every iterations in the same format as the output, named after the
+iteration, e.g. frame00010.png, with the lowest cost path found so far highlighted.
+With format png, the frames are also assembled into an animated GIF,
+animation.gif. Edge colors are scaled to the pheromone in each frame unless
+scalemax is set.
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jsolmon/acogo"
+)
+
+func main() {
+	// acogo serve, acogo api and acogo generate take the same options as
+	// acogo
+	var command string
+	if len(os.Args) > 1 && (os.Args[1] == "serve" || os.Args[1] == "api" || os.Args[1] == "generate") {
+		command = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	cfg := acogo.DefaultConfig()
+
+	flag.IntVar(&cfg.AntCount, "antcount", cfg.AntCount, "the number of ants to create")
+	flag.Float64Var(&cfg.DepositAmt, "depositamt", cfg.DepositAmt, "amount of pheromone deposited by ant")
+	var depositRule = flag.String("deposit", "fixed", "how ants deposit pheromone, one of fixed, quality, rank or elitist")
+	var elite = flag.Float64("elite", 5.0, "the weight of the extra elitist deposit on the best path found so far")
+	var rankAnts = flag.Int("rankants", 6, "the weight of the best path found so far in rank deposits, one more than the number of ants that deposit")
+	flag.IntVar(&cfg.Iterations, "iterations", cfg.Iterations, "the number times each ant will find the goal node")
+	var decayFactor = flag.Float64("decay", 0.3, "the amount of pheromone dissipated after each round, or the proportion for multiplicative evaporation")
+	var evaporation = flag.String("evaporation", "subtractive", "how pheromone dissipates after each round, one of subtractive, multiplicative or visited")
+	var dimension = flag.Int("dimension", 6, "the number of nodes on each side of the square graph")
+	var topology = flag.String("topology", "square", "the lattice or random graph the graph is generated on, one of square, rect, grid4, hex, torus, triangular, gnp, ba, ws or geometric")
+	var width = flag.Int("width", 0, "the number of nodes in each row of rect, grid4, hex, torus and triangular graphs, if unset dimension")
+	var height = flag.Int("height", 0, "the number of rows of rect, grid4, hex, torus and triangular graphs, if unset dimension")
+	var diagonals = flag.Bool("diagonals", true, "give torus and map nodes edges to diagonally adjacent nodes")
+	var nodes = flag.Int("nodes", 50, "the number of nodes in gnp, ba, ws and geometric graphs")
+	var edgeProb = flag.Float64("p", 0.1, "the probability that each pair of nodes in a gnp graph is joined")
+	var attach = flag.Int("m", 2, "the number of edges each node added to a ba graph brings")
+	var ringNeighbors = flag.Int("k", 4, "the number of nearest neighbors each node of a ws graph starts joined to, an even number")
+	var rewire = flag.Float64("rewire", 0.1, "the probability that each edge of a ws graph is rewired")
+	var radius = flag.Float64("radius", 1.5, "the distance within which nodes of a geometric graph are joined")
+	var graphSeed = flag.Int64("graphseed", 1, "the seed for drawing gnp, ba, ws and geometric graphs")
+	var startNode = flag.Int("start", 0, "the index of the node where ants begin")
+	var goalNode = flag.Int("goal", -1, "the index of the vertex ants are trying to reach, if unset will default to the last node")
+	var graphFile = flag.String("graph", "", "a DOT file to read the graph from, if unset a square graph is generated")
+	var mapFile = flag.String("map", "", "an ASCII map of a grid world to build the graph from, with . for free cells, # for walls, H for home, G for goal and 1-9 for the cost of entering a cell")
+	flag.StringVar(&cfg.Ant, "ant", cfg.Ant, "the type of ant to run, one of simple, as, acs or mmas")
+	flag.Float64Var(&cfg.Alpha, "alpha", cfg.Alpha, "the relative influence of pheromone on as, acs and mmas ants' path decisions")
+	flag.Float64Var(&cfg.Beta, "beta", cfg.Beta, "the relative influence of edge cost on as, acs and mmas ants' path decisions")
+	flag.Float64Var(&cfg.Q0, "q0", cfg.Q0, "the probability that acs ants choose the best edge")
+	flag.Float64Var(&cfg.Xi, "xi", cfg.Xi, "the rate at which acs ants evaporate pheromone on edges they cross")
+	flag.Float64Var(&cfg.PBest, "pbest", cfg.PBest, "the probability of mmas ants taking the best path once converged, used to derive the lower pheromone bound")
+	var mmasBest = flag.String("mmasbest", "iteration", "which mmas ant deposits pheromone, one of iteration or global")
+	flag.IntVar(&cfg.Stagnation, "stagnation", cfg.Stagnation, "the number of iterations without a better path after which mmas pheromone is reset, 0 to never reset")
+	flag.IntVar(&cfg.MaxSteps, "maxsteps", 0, "the number of steps after which an ant gives up on reaching the goal, 0 for no limit")
+	flag.Int64Var(&cfg.Seed, "seed", 0, "the seed for the source of randomness, if unset the current time is used")
+	dotOpts := acogo.DefaultDotOptions()
+	flag.StringVar(&dotOpts.Scale, "scale", dotOpts.Scale, "how pheromone is scaled onto edge colors, one of linear, log or percentile")
+	flag.Float64Var(&dotOpts.Max, "scalemax", 0, "the pheromone drawn in the darkest color by linear and log scales, if unset the most pheromone on any edge")
+	flag.Float64Var(&dotOpts.Percentile, "percentile", dotOpts.Percentile, "the percentile of edge pheromone drawn in the darkest color by the percentile scale")
+	var colorMap = flag.String("colormap", "alpha", "the colors edges are drawn in, one of alpha, blues, heat, viridis, coolwarm or purplegreen")
+	flag.BoolVar(&dotOpts.Labels, "labels", false, "label each edge with its pheromone")
+	flag.BoolVar(&dotOpts.Legend, "legend", dotOpts.Legend, "draw a legend of the edge color scale")
+	var format = flag.String("format", "dot", "the format of the output, one of dot, svg or png")
+	var output = flag.String("o", "", "a file to write the output to, if unset it is written to stdout")
+	var snapshotEvery = flag.Int("snapshot-every", 0, "write a frame of the graph every this many iterations, 0 for no frames")
+	var snapshotDir = flag.String("snapshot-dir", "snapshots", "the directory frames are written to")
+	var tui = flag.Bool("tui", false, "draw a live heat map of pheromone on the graph to stderr while the colony runs")
+	var addr = flag.String("addr", ":8080", "the address acogo serve and acogo api listen on")
+	var maxJobs = flag.Int("maxjobs", 4, "the number of jobs acogo api runs at once")
+	var maxQueued = flag.Int("maxqueued", 16, "the number of jobs which may wait to run in acogo api")
+	var interval = flag.Duration("interval", 100*time.Millisecond, "the time acogo serve waits between iterations")
+	var highlight = flag.Bool("highlight", true, "draw the consensus path and the best path found by any ant in distinct colors")
+	var statsFile = flag.String("stats", "", "a file to write per-iteration statistics to")
+	var statsFormat = flag.String("statsformat", "csv", "the format of the statistics file, one of csv or json")
+	flag.BoolVar(&cfg.Deterministic, "deterministic", false, "move ants one at a time in a fixed order so runs with the same seed produce the same output")
+	var checkpoint = flag.String("checkpoint", "", "a file to periodically write the state of the colony to")
+	var checkpointEvery = flag.Int("checkpointevery", 100, "write a checkpoint every this many iterations")
+	var resume = flag.String("resume", "", "a checkpoint file to resume the colony from")
+	var warmStart = flag.String("warmstart", "", "a DOT file written by an earlier run to take the initial pheromone on each edge from")
+	var eventsFile = flag.String("events", "", "a file of events, such as blocking edges, to apply to the graph between iterations")
+	var window = flag.Int("convergewindow", acogo.DefaultConvergenceWindow, "the number of iterations the consensus path must stay the same after events for the colony to have re-converged")
+
+	flag.Parse()
+	if command == "api" {
+		if *maxJobs < 1 || *maxQueued < 0 {
+			usageError("maxjobs must be at least 1 and maxqueued must not be negative")
+		}
+		fmt.Fprintf(os.Stderr, "acogo: serving job api on %v\n", *addr)
+		if err := http.ListenAndServe(*addr, acogo.NewJobServer(*maxJobs, *maxQueued)); err != nil {
+			fmt.Fprintf(os.Stderr, "acogo: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *topology == "square" || *width == 0 {
+		*width = *dimension
+	}
+	if *topology == "square" || *height == 0 {
+		*height = *dimension
+	}
+	// random graphs have nodes nodes, and lattices width * height
+	size := *width * *height
+	var generator acogo.Generator
+	switch *topology {
+	case "gnp":
+		generator = acogo.ErdosRenyi{N: *nodes, P: *edgeProb}
+	case "ba":
+		generator = acogo.BarabasiAlbert{N: *nodes, M: *attach}
+	case "ws":
+		generator = acogo.WattsStrogatz{N: *nodes, K: *ringNeighbors, Beta: *rewire}
+	case "geometric":
+		generator = acogo.RandomGeometric{N: *nodes, Radius: *radius}
+	}
+	if generator != nil {
+		size = *nodes
+	}
+	if *goalNode == -1 {
+		*goalNode = size - 1
+	}
+	ev, err := acogo.NewEvaporation(*evaporation, *decayFactor)
+	if err != nil {
+		usageError("%v", err)
+	}
+	cfg.Deposit, err = acogo.NewDeposit(*depositRule, cfg.DepositAmt, *elite, *rankAnts)
+	if err != nil {
+		usageError("%v", err)
+	}
+	if cfg.Ant == acogo.AntACS || cfg.Ant == acogo.AntMMAS {
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "deposit" || f.Name == "evaporation" {
+				usageError("%v cannot be used with %v ants, which update pheromone by their own rules", f.Name, cfg.Ant)
+			}
+		})
+	}
+	if *statsFormat != "csv" && *statsFormat != "json" {
+		usageError("unknown stats format %q", *statsFormat)
+	}
+	switch *format {
+	case "dot", "svg", "png":
+	default:
+		usageError("unknown format %q", *format)
+	}
+	if *window < 1 {
+		usageError("convergewindow must be at least 1, got %v", *window)
+	}
+	if command == "serve" && *eventsFile != "" {
+		usageError("events cannot be used with acogo serve")
+	}
+	if *graphFile != "" && *mapFile != "" {
+		usageError("graph and map cannot both be set")
+	}
+	if *checkpointEvery < 1 {
+		usageError("checkpointevery must be at least 1, got %v", *checkpointEvery)
+	}
+	if *snapshotEvery < 0 {
+		usageError("snapshot-every must not be negative, got %v", *snapshotEvery)
+	}
+	switch dotOpts.Scale {
+	case acogo.ScaleLinear, acogo.ScaleLog, acogo.ScalePercentile:
+	default:
+		usageError("unknown scale %q", dotOpts.Scale)
+	}
+	if dotOpts.Percentile < 0 || dotOpts.Percentile > 100 {
+		usageError("percentile must be between 0 and 100, got %v", dotOpts.Percentile)
+	}
+	if m, ok := acogo.ColorMaps[*colorMap]; ok {
+		dotOpts.ColorMap = m
+	} else {
+		usageError("unknown color map %q", *colorMap)
+	}
+	switch *mmasBest {
+	case "iteration":
+	case "global":
+		cfg.MMASGlobalBest = true
+	default:
+		usageError("unknown mmas best ant %q", *mmasBest)
+	}
+
+	var graph *acogo.Graph
+	var colony *acogo.Colony
+	if *resume != "" {
+		cp, err := acogo.ReadCheckpointFile(*resume)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "acogo: reading %v: %v\n", *resume, err)
+			os.Exit(1)
+		}
+		// iterations, if given, is the total for the resumed colony
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "iterations" {
+				cp.Config.Iterations = cfg.Iterations
+			}
+		})
+		colony, err = cp.Colony()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "acogo: resuming from %v: %v\n", *resume, err)
+			os.Exit(1)
+		}
+		graph, cfg = colony.Graph, colony.Config
+	} else {
+		// create graph
+		if *graphFile != "" {
+			var err error
+			graph, err = acogo.ReadDotFile(*graphFile, *decayFactor)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "acogo: reading %v: %v\n", *graphFile, err)
+				os.Exit(1)
+			}
+		} else if *mapFile != "" {
+			m, err := acogo.ReadMapFile(*mapFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "acogo: reading %v: %v\n", *mapFile, err)
+				os.Exit(1)
+			}
+			m.Diagonals = *diagonals
+			graph = m.Graph(*decayFactor)
+		} else if generator != nil {
+			var err error
+			graph, err = acogo.NewRandomGraph(generator, *graphSeed, *startNode, *goalNode, *decayFactor)
+			if err != nil {
+				usageError("%v", err)
+			}
+		} else {
+			t, err := acogo.NewTopology(*topology, *width, *height, *diagonals)
+			if err != nil {
+				usageError("%v", err)
+			}
+			graph = acogo.NewTopologyGraph(t, *startNode, *goalNode, *decayFactor)
+		}
+		graph.Evaporation = ev
+
+		if *warmStart != "" {
+			src, err := acogo.ReadDotFile(*warmStart, *decayFactor)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "acogo: reading %v: %v\n", *warmStart, err)
+				os.Exit(1)
+			}
+			if graph.CopyPheromone(src) == 0 {
+				fmt.Fprintf(os.Stderr, "acogo: %v has no edges between nodes of the graph\n", *warmStart)
+				os.Exit(1)
+			}
+		}
+
+		colony, err = acogo.NewColony(graph, cfg)
+		if err != nil {
+			usageError("%v", err)
+		}
+	}
+
+	if *eventsFile != "" {
+		schedule, err := acogo.ReadEventsFile(*eventsFile, graph)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "acogo: reading %v: %v\n", *eventsFile, err)
+			os.Exit(1)
+		}
+		schedule.Window = *window
+		colony.Schedule = schedule
+	}
+
+	if command == "generate" {
+		dotOpts.Legend = false
+		if err := writeOutput(graph, *format, *output, dotOpts); err != nil {
+			fmt.Fprintf(os.Stderr, "acogo: writing output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var stats acogo.StatsWriter
+	var statsErr error
+	if *statsFile != "" {
+		f, err := os.Create(*statsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "acogo: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		if *statsFormat == "json" {
+			stats = acogo.NewJSONStatsWriter(f)
+		} else {
+			stats = acogo.NewCSVStatsWriter(f)
+		}
+	}
+
+	var snapshots *acogo.Snapshotter
+	var snapshotErr error
+	if *snapshotEvery > 0 {
+		if err := os.MkdirAll(*snapshotDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "acogo: %v\n", err)
+			os.Exit(1)
+		}
+		snapshots = acogo.NewSnapshotter(*snapshotDir, *snapshotEvery, *format, dotOpts)
+	}
+
+	var heatMap *acogo.HeatMap
+	var progress struct {
+		sync.Mutex
+		stats  acogo.IterationStats
+		steps  int
+		failed int
+	}
+	if *tui {
+		heatMap, err = acogo.NewHeatMap(os.Stderr, graph, dotOpts)
+		if err != nil {
+			usageError("tui: %v", err)
+		}
+	}
+
+	var checkpointErr error
+	colony.OnIteration = func(s acogo.IterationStats) {
+		if heatMap != nil {
+			progress.Lock()
+			progress.stats, progress.steps, progress.failed = s, len(colony.Best.Path)-1, colony.Failed
+			progress.Unlock()
+		}
+		if stats != nil && statsErr == nil {
+			statsErr = stats.WriteStats(s)
+		}
+		if snapshots != nil && snapshotErr == nil {
+			// highlight the best path found so far in each frame
+			snapshots.Options.Highlights = nil
+			if *highlight && colony.Best.Path != nil {
+				h := acogo.BestHighlight
+				h.Path = colony.Best.Path
+				snapshots.Options.Highlights = []acogo.Highlight{h}
+			}
+			snapshotErr = snapshots.Snapshot(graph, s.Iteration)
+		}
+		if *checkpoint != "" && checkpointErr == nil && s.Iteration%*checkpointEvery == 0 {
+			checkpointErr = colony.WriteCheckpointFile(*checkpoint)
+		}
+	}
+
+	// write the final checkpoint once the colony finishes or is interrupted
+	writeCheckpoint := func() error {
+		if checkpointErr == nil {
+			checkpointErr = colony.WriteCheckpointFile(*checkpoint)
+		}
+		if checkpointErr != nil {
+			return fmt.Errorf("writing checkpoint %v: %v", *checkpoint, checkpointErr)
+		}
+		return nil
+	}
+
+	// flush the stats and write the animation once the colony finishes
+	finish := func() error {
+		if stats != nil {
+			if statsErr == nil {
+				statsErr = stats.Flush()
+			}
+			if statsErr != nil {
+				return fmt.Errorf("writing %v: %v", *statsFile, statsErr)
+			}
+		}
+		if snapshots != nil {
+			if snapshotErr == nil {
+				snapshotErr = snapshots.WriteGIF(filepath.Join(*snapshotDir, "animation.gif"))
+			}
+			if snapshotErr != nil {
+				return fmt.Errorf("writing snapshots: %v", snapshotErr)
+			}
+		}
+		return nil
+	}
+
+	if command == "serve" {
+		fmt.Fprintf(os.Stderr, "acogo: serving on %v\n", *addr)
+		onDone := func() {
+			if *checkpoint != "" {
+				if err := writeCheckpoint(); err != nil {
+					fmt.Fprintf(os.Stderr, "acogo: %v\n", err)
+				}
+			}
+			if err := finish(); err != nil {
+				fmt.Fprintf(os.Stderr, "acogo: %v\n", err)
+			}
+		}
+		if err := serveColony(*addr, colony, *interval, onDone); err != nil {
+			fmt.Fprintf(os.Stderr, "acogo: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// redraw the heat map until the colony finishes
+	drawHeatMap := func() {
+		progress.Lock()
+		status := fmt.Sprintf("iteration %v/%v  best %.4g (%v steps)  ants in flight %v  failed %v",
+			progress.stats.Iteration, cfg.Iterations, progress.stats.GlobalBestCost, progress.steps, colony.InFlight(), progress.failed)
+		progress.Unlock()
+		if err := heatMap.Draw(status); err != nil {
+			fmt.Fprintf(os.Stderr, "acogo: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	done := make(chan struct{})
+	drawn := make(chan struct{})
+	if heatMap != nil {
+		go func() {
+			defer close(drawn)
+			ticker := time.NewTicker(100 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					drawHeatMap()
+				case <-done:
+					drawHeatMap()
+					return
+				}
+			}
+		}()
+	}
+
+	// with a checkpoint, an interrupted colony stops after the current
+	// iteration and writes its state so it can be resumed
+	ctx := context.Background()
+	if *checkpoint != "" {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+	}
+	result, err := colony.Run(ctx)
+	close(done)
+	if heatMap != nil {
+		<-drawn
+	}
+	if *checkpoint != "" {
+		if err := writeCheckpoint(); err != nil {
+			fmt.Fprintf(os.Stderr, "acogo: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		if *checkpoint != "" && ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "acogo: stopped after iteration %v, resume with -resume %v\n", result.Iterations, *checkpoint)
+		} else {
+			fmt.Fprintf(os.Stderr, "acogo: %v\n", err)
+		}
+		os.Exit(1)
+	}
+	if err := finish(); err != nil {
+		fmt.Fprintf(os.Stderr, "acogo: %v\n", err)
+		os.Exit(1)
+	}
+	if result.FailedAnts > 0 {
+		fmt.Fprintf(os.Stderr, "acogo: %v ants gave up without reaching the goal\n", result.FailedAnts)
+	}
+
+	if colony.Schedule != nil {
+		for _, a := range colony.Schedule.Adaptations {
+			if n := a.Iterations(); n == -1 {
+				fmt.Fprintf(os.Stderr, "events at iteration %v: did not re-converge\n", a.Iteration)
+			} else {
+				fmt.Fprintf(os.Stderr, "events at iteration %v: re-converged after %v iterations to %v (cost %.4g)\n", a.Iteration, n, graph.PathString(a.Path), graph.PathCost(a.Path))
+			}
+		}
+	}
+
+	consensus, err := graph.ConsensusPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "acogo: %v: %v\n", err, graph.PathString(consensus))
+	} else {
+		fmt.Fprintf(os.Stderr, "consensus path: %v (cost %.4g)\n", graph.PathString(consensus), graph.PathCost(consensus))
+		h := acogo.ConsensusHighlight
+		h.Path = consensus
+		dotOpts.Highlights = append(dotOpts.Highlights, h)
+	}
+	if result.BestPath != nil {
+		fmt.Fprintf(os.Stderr, "best path: %v (cost %.4g)\n", graph.PathString(result.BestPath), result.BestCost)
+		h := acogo.BestHighlight
+		h.Path = result.BestPath
+		dotOpts.Highlights = append(dotOpts.Highlights, h)
+	}
+	if !*highlight {
+		dotOpts.Highlights = nil
+	}
+
+	if err := writeOutput(graph, *format, *output, dotOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "acogo: writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// writeOutput writes graph in format to the file output, or to stdout if
+// output is empty.
+func writeOutput(graph *acogo.Graph, format, output string, opts acogo.DotOptions) error {
+	out := os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	var err error
+	switch format {
+	case "svg":
+		err = acogo.WriteSVG(out, graph, opts)
+	case "png":
+		err = acogo.WritePNG(out, graph, opts)
+	default:
+		_, err = fmt.Fprint(out, acogo.ToDot(graph, opts).String())
+	}
+	return err
+}
+
+// usageError prints an error about the command line options to stderr and
+// exits.
+func usageError(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "acogo: "+format+"\n", args...)
+	os.Exit(2)
+}
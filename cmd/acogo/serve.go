@@ -0,0 +1,293 @@
+// Copyright (c) 2014 Joanna Solmon. All rights reserved.
+// Use of this source code is governed by the MIT License found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jsolmon/acogo"
+)
+
+// server runs a colony one iteration at a time and streams the pheromone on
+// each edge and the stats for each iteration to browsers as Server-Sent
+// Events. The colony can be paused, resumed and stepped through over HTTP.
+type server struct {
+	colony   *acogo.Colony
+	interval time.Duration
+	// every edge in the graph, in the order they are numbered in messages
+	edges []*acogo.Edge
+
+	mu sync.Mutex
+	// signalled when paused or steps change
+	wake *sync.Cond
+	// whether the colony is paused, and how many iterations to run while
+	// paused
+	paused bool
+	steps  int
+	// whether the colony has run all its iterations
+	done bool
+	// if set, onDone is called once the colony has run all its iterations
+	onDone func()
+	// pheromone on each edge, the stats and the best path found as of the
+	// last iteration
+	pheromone []float64
+	stats     acogo.IterationStats
+	bestPath  []int
+	// channels of browsers listening for events
+	clients map[chan []byte]bool
+}
+
+// graphMessage describes the graph for the page to draw.
+type graphMessage struct {
+	Nodes []nodeMessage `json:"nodes"`
+	// start and end node of each edge
+	Edges [][2]int `json:"edges"`
+}
+
+type nodeMessage struct {
+	Name string `json:"name"`
+	// position of the node, or nil if it has none
+	Pos  *[2]float64 `json:"pos"`
+	Type string      `json:"type"`
+}
+
+// stateMessage is sent to each browser when it connects and after each
+// iteration. Pheromone holds [edge, pheromone] pairs for every edge when a
+// browser connects, and only for edges whose pheromone changed after each
+// iteration.
+type stateMessage struct {
+	Pheromone [][2]float64         `json:"pheromone"`
+	Stats     acogo.IterationStats `json:"stats"`
+	BestPath  []int                `json:"best_path"`
+	Paused    bool                 `json:"paused"`
+	Done      bool                 `json:"done"`
+}
+
+// newServer creates a server for colony, waiting interval between
+// iterations while it runs.
+func newServer(colony *acogo.Colony, interval time.Duration) *server {
+	s := &server{
+		colony:   colony,
+		interval: interval,
+		clients:  make(map[chan []byte]bool),
+	}
+	s.wake = sync.NewCond(&s.mu)
+	for _, n := range colony.Graph.Nodes {
+		for _, e := range n.OutEdges {
+			s.edges = append(s.edges, e)
+			s.pheromone = append(s.pheromone, e.Pheromone())
+		}
+	}
+
+	onIteration := colony.OnIteration
+	colony.OnIteration = func(stats acogo.IterationStats) {
+		if onIteration != nil {
+			onIteration(stats)
+		}
+		s.mu.Lock()
+		s.stats, s.bestPath = stats, append([]int(nil), colony.Best.Path...)
+		s.mu.Unlock()
+	}
+	return s
+}
+
+// serveColony runs colony and serves the dashboard on addr until the server
+// fails, calling onDone once the colony has run all its iterations.
+func serveColony(addr string, colony *acogo.Colony, interval time.Duration, onDone func()) error {
+	s := newServer(colony, interval)
+	s.onDone = onDone
+	go s.run()
+	return http.ListenAndServe(addr, s.handler())
+}
+
+// handler returns the HTTP handler for the dashboard.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveIndex)
+	mux.HandleFunc("/graph", s.serveGraph)
+	mux.HandleFunc("/events", s.serveEvents)
+	mux.HandleFunc("/pause", s.control(func() { s.paused = true }))
+	mux.HandleFunc("/resume", s.control(func() { s.paused = false }))
+	mux.HandleFunc("/step", s.control(func() { s.paused, s.steps = true, s.steps+1 }))
+	return mux
+}
+
+// run runs the colony's iterations, waiting while it is paused, and
+// broadcasts the state after each one.
+func (s *server) run() {
+	defer s.colony.Stop()
+	for s.colony.Iteration < s.colony.Config.Iterations {
+		s.mu.Lock()
+		for s.paused && s.steps == 0 {
+			s.wake.Wait()
+		}
+		if s.paused {
+			s.steps--
+		}
+		s.mu.Unlock()
+
+		s.colony.Step()
+		s.broadcastIteration()
+		if !s.isPaused() {
+			time.Sleep(s.interval)
+		}
+	}
+	if s.onDone != nil {
+		s.onDone()
+	}
+
+	s.mu.Lock()
+	s.done = true
+	s.broadcast(s.state(false))
+	s.mu.Unlock()
+}
+
+func (s *server) isPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// broadcastIteration records the pheromone on each edge after an iteration
+// and sends the edges which changed to every browser.
+func (s *server) broadcastIteration() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changed [][2]float64
+	for i, e := range s.edges {
+		if p := e.Pheromone(); p != s.pheromone[i] {
+			s.pheromone[i] = p
+			changed = append(changed, [2]float64{float64(i), p})
+		}
+	}
+
+	msg := s.state(false)
+	msg.Pheromone = changed
+	s.broadcast(msg)
+}
+
+// state returns the current state, with the pheromone on every edge if all
+// is set. s.mu must be held.
+func (s *server) state(all bool) stateMessage {
+	msg := stateMessage{Stats: s.stats, BestPath: s.bestPath, Paused: s.paused, Done: s.done}
+	if all {
+		for i, p := range s.pheromone {
+			msg.Pheromone = append(msg.Pheromone, [2]float64{float64(i), p})
+		}
+	}
+	return msg
+}
+
+// broadcast sends msg to every browser, dropping browsers which have fallen
+// behind. s.mu must be held.
+func (s *server) broadcast(msg stateMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	for c := range s.clients {
+		select {
+		case c <- data:
+		default:
+			delete(s.clients, c)
+			close(c)
+		}
+	}
+}
+
+// control returns a handler for POST requests which applies change to the
+// paused state and wakes the colony.
+func (s *server) control(change func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.mu.Lock()
+		change()
+		s.wake.Broadcast()
+		// let browsers know the colony was paused or resumed
+		s.broadcast(s.state(false))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, dashboardHTML)
+}
+
+func (s *server) serveGraph(w http.ResponseWriter, r *http.Request) {
+	g := s.colony.Graph
+	var msg graphMessage
+	for _, n := range g.Nodes {
+		nm := nodeMessage{Name: n.Name, Type: "path"}
+		if n.HasPos {
+			nm.Pos = &[2]float64{n.X, n.Y}
+		}
+		switch n.Type {
+		case acogo.Home:
+			nm.Type = "home"
+		case acogo.Goal:
+			nm.Type = "goal"
+		}
+		msg.Nodes = append(msg.Nodes, nm)
+	}
+	for _, e := range s.edges {
+		msg.Edges = append(msg.Edges, [2]int{e.StartNodeId, e.EndNodeId})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msg)
+}
+
+// serveEvents streams the state to a browser, starting with the pheromone
+// on every edge.
+func (s *server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	c := make(chan []byte, 64)
+	s.mu.Lock()
+	msg := s.state(true)
+	s.clients[c] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if s.clients[c] {
+			delete(s.clients, c)
+			close(c)
+		}
+		s.mu.Unlock()
+	}()
+
+	data, _ := json.Marshal(msg)
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	flusher.Flush()
+	for {
+		select {
+		case data, ok := <-c:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2014 Joanna Solmon. All rights reserved.
+// Use of this source code is governed by the MIT License found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jsolmon/acogo"
+)
+
+// TestServe ensures that the server streams the pheromone on every edge when
+// a browser connects and only runs iterations when stepped while paused.
+func TestServe(t *testing.T) {
+	cfg := acogo.DefaultConfig()
+	cfg.Iterations = 3
+	g := acogo.NewGraph(3, 0, 8, 0.3)
+	colony, err := acogo.NewColony(g, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newServer(colony, 0)
+	s.paused = true
+	finished := make(chan struct{})
+	s.onDone = func() { close(finished) }
+	go s.run()
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	next := func() stateMessage {
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasPrefix(line, "data: ") {
+				var msg stateMessage
+				if err := json.Unmarshal([]byte(line[len("data: "):]), &msg); err != nil {
+					t.Fatal(err)
+				}
+				return msg
+			}
+		}
+	}
+
+	edges := 0
+	for _, n := range g.Nodes {
+		edges += len(n.OutEdges)
+	}
+	if msg := next(); len(msg.Pheromone) != edges || !msg.Paused || msg.Stats.Iteration != 0 {
+		t.Error(fmt.Sprintf("expected paused state with pheromone on %v edges but got %+v", edges, msg))
+	}
+
+	for i := 1; i <= 3; i++ {
+		if _, err := http.Post(ts.URL+"/step", "", nil); err != nil {
+			t.Fatal(err)
+		}
+		// skip the message acknowledging the step
+		msg := next()
+		for msg.Stats.Iteration != i {
+			msg = next()
+		}
+		if len(msg.Pheromone) == 0 || len(msg.BestPath) == 0 {
+			t.Error(fmt.Sprintf("expected pheromone changes and a best path after step %v but got %+v", i, msg))
+		}
+	}
+	if msg := next(); !msg.Done {
+		t.Error(fmt.Sprintf("expected done after all iterations but got %+v", msg))
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("expected onDone to be called before the colony is reported done")
+	}
+
+	if resp, err := http.Get(ts.URL + "/step"); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Error("expected GET /step to be rejected")
+	}
+}
@@ -0,0 +1,485 @@
+package acogo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Types of ants a Colony can run.
+const (
+	AntSimple = "simple"
+	AntAS     = "as"
+	AntACS    = "acs"
+	AntMMAS   = "mmas"
+)
+
+// Config holds the parameters of a Colony.
+type Config struct {
+	// Number of ants run each iteration
+	AntCount int `json:"ant_count"`
+	// Number of times each ant runs from home to goal
+	Iterations int `json:"iterations"`
+	// Type of ant to run, one of AntSimple, AntAS, AntACS or AntMMAS
+	Ant string `json:"ant"`
+	// Amount of pheromone each ant deposits on its path
+	DepositAmt float64 `json:"deposit_amt"`
+	// How simple and as ants deposit pheromone. If nil, each ant deposits
+	// DepositAmt on its path.
+	Deposit Deposit `json:"-"`
+
+	// Relative influence of pheromone on as, acs and mmas ants' path decisions
+	Alpha float64 `json:"alpha"`
+	// Relative influence of edge cost on as, acs and mmas ants' path decisions
+	Beta float64 `json:"beta"`
+	// Probability that acs ants choose the best edge
+	Q0 float64 `json:"q0"`
+	// Rate at which acs ants evaporate pheromone on edges they cross
+	Xi float64 `json:"xi"`
+	// Probability of mmas ants taking the best path once converged
+	PBest float64 `json:"pbest"`
+	// Whether the global-best rather than iteration-best mmas ant deposits
+	// pheromone
+	MMASGlobalBest bool `json:"mmas_global_best"`
+	// Number of iterations without a better path after which mmas pheromone
+	// is reset, 0 to never reset
+	Stagnation int `json:"stagnation"`
+	// Greatest number of steps an ant may take before giving up on reaching
+	// the goal, 0 for no limit. Ants which give up do not deposit pheromone.
+	MaxSteps int `json:"max_steps"`
+
+	// Seed for the colony's source of randomness. If 0, the colony is seeded
+	// from the current time.
+	Seed int64 `json:"seed"`
+	// Whether ants are moved through the graph one at a time in a fixed order,
+	// each with its own source of randomness seeded from Seed, rather than
+	// concurrently. Deterministic colonies with the same Seed produce the
+	// same results.
+	Deterministic bool `json:"deterministic"`
+}
+
+// DefaultConfig returns the default Config used by the acogo command.
+func DefaultConfig() Config {
+	return Config{
+		AntCount:   20,
+		Iterations: 500,
+		Ant:        AntSimple,
+		DepositAmt: 1.0,
+		Alpha:      1.0,
+		Beta:       2.0,
+		Q0:         0.9,
+		Xi:         0.1,
+		PBest:      0.05,
+		Stagnation: 50,
+	}
+}
+
+// Colony runs ants over a Graph, updating the pheromone on its edges after
+// each iteration.
+type Colony struct {
+	// Graph the ants travel on
+	Graph *Graph
+	// Parameters of the colony
+	Config Config
+	// Number of iterations completed
+	Iteration int
+	// Lowest cost path found so far
+	Best Best
+	// Number of ants which gave up before reaching the goal
+	Failed int
+	// If set, OnIteration is called with the stats for each iteration once
+	// pheromone on the graph has been updated.
+	OnIteration func(IterationStats)
+	// If set, events applied to the graph between iterations by
+	// ApplyEvents
+	Schedule *Schedule
+
+	// source of randomness shared by all ants
+	random RandomSource
+	// source underlying random, which counts the values drawn from it so
+	// the colony can be checkpointed
+	source *countingSource
+	// global pheromone update for mmas ants
+	mmas *MMAS
+	// whether the graph and random source have been started
+	started bool
+	// number of ants moving through the graph, updated atomically
+	inFlight int32
+}
+
+// NewColony creates a Colony which runs ants over g with the parameters in
+// c. NewColony returns an error if the parameters are invalid or g is not
+// valid, as described by Graph.Validate.
+func NewColony(g *Graph, c Config) (*Colony, error) {
+	if err := g.validate(c.MaxSteps > 0); err != nil {
+		return nil, err
+	}
+	switch c.Ant {
+	case AntSimple, AntAS, AntACS, AntMMAS:
+	default:
+		return nil, fmt.Errorf("unknown ant type %q", c.Ant)
+	}
+	if c.AntCount < 1 {
+		return nil, errors.New("colony must have at least one ant")
+	}
+	if c.MaxSteps < 0 {
+		return nil, errors.New("max steps must not be negative")
+	}
+	if c.Alpha < 0 {
+		return nil, fmt.Errorf("alpha must not be negative, got %v", c.Alpha)
+	}
+	if c.Beta < 0 {
+		return nil, fmt.Errorf("beta must not be negative, got %v", c.Beta)
+	}
+	if c.Ant == AntACS {
+		if c.Q0 < 0 || c.Q0 > 1 {
+			return nil, fmt.Errorf("q0 must be between 0 and 1, got %v", c.Q0)
+		}
+		if c.Xi < 0 || c.Xi > 1 {
+			return nil, fmt.Errorf("xi must be between 0 and 1, got %v", c.Xi)
+		}
+		// acs uses the graph's decay as the rate of its global update
+		if g.DecayFactor <= 0 || g.DecayFactor > 1 {
+			return nil, fmt.Errorf("decay must be greater than 0 and at most 1 for acs ants, got %v", g.DecayFactor)
+		}
+	}
+	if c.Ant == AntMMAS {
+		// mmas uses the graph's decay as the evaporation rate
+		if g.DecayFactor <= 0 || g.DecayFactor > 1 {
+			return nil, fmt.Errorf("decay must be greater than 0 and at most 1 for mmas ants, got %v", g.DecayFactor)
+		}
+		if c.PBest <= 0 || c.PBest >= 1 {
+			return nil, fmt.Errorf("pbest must be between 0 and 1, exclusive, got %v", c.PBest)
+		}
+	}
+	if c.Deposit == nil {
+		c.Deposit = FixedDeposit{}
+	}
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+
+	source := newCountingSource(c.Seed)
+	colony := &Colony{
+		Graph:  g,
+		Config: c,
+		random: RandomSource{rand: rand.New(source)},
+		source: source,
+	}
+	if c.Ant == AntMMAS {
+		// mmas uses the graph's decay as the evaporation rate
+		colony.mmas = NewMMAS(c.DepositAmt, g.DecayFactor, c.PBest, c.MMASGlobalBest, c.Stagnation)
+	}
+	return colony, nil
+}
+
+// Result is the outcome of running a Colony.
+type Result struct {
+	// Number of iterations completed
+	Iterations int
+	// Lowest cost path found and its cost
+	BestPath []int
+	BestCost float64
+	// Number of ants which gave up before reaching the goal
+	FailedAnts int
+	// Path found by following the edges with the most pheromone from home,
+	// or nil if it does not reach the goal
+	ConsensusPath []int
+}
+
+// Run runs the colony until it has completed c.Config.Iterations iterations
+// or ctx is done, applying the events in c.Schedule before each iteration.
+// If ctx is done first, Run abandons the iteration in progress and returns
+// the result of those completed along with ctx.Err(), and if events cannot
+// be applied, the result so far along with the error. Run stops the colony
+// before returning.
+func (c *Colony) Run(ctx context.Context) (*Result, error) {
+	defer c.Stop()
+	for c.Iteration < c.Config.Iterations {
+		select {
+		case <-ctx.Done():
+			return c.Result(), ctx.Err()
+		default:
+		}
+		if err := c.ApplyEvents(); err != nil {
+			return c.Result(), err
+		}
+		if err := c.step(ctx); err != nil {
+			return c.Result(), err
+		}
+	}
+	return c.Result(), nil
+}
+
+// Result returns the result of the iterations run so far.
+func (c *Colony) Result() *Result {
+	result := &Result{
+		Iterations: c.Iteration,
+		BestPath:   c.Best.Path,
+		BestCost:   c.Best.Cost,
+		FailedAnts: c.Failed,
+	}
+	if path, err := c.Graph.ConsensusPath(); err == nil {
+		result.ConsensusPath = path
+	}
+	return result
+}
+
+// Step runs a single iteration, sending c.Config.AntCount ants from home to
+// goal and then updating pheromone on the graph based on their paths. The
+// first Step after the colony is created or stopped starts the go routines
+// which move ants through the graph, which run until Stop is called.
+func (c *Colony) Step() {
+	c.step(context.Background())
+}
+
+// step runs a single iteration as Step does unless ctx is done before every
+// ant is done moving, in which case step stops the colony, abandons the ants
+// still moving and returns ctx.Err(). The ants of an abandoned iteration lay
+// down no pheromone and the iteration is not counted, though ACS ants will
+// have updated the edges they crossed.
+func (c *Colony) step(ctx context.Context) error {
+	if c.Config.Deterministic {
+		return c.stepDeterministic(ctx)
+	}
+	if !c.started {
+		c.Graph.Run()
+		c.random.RequestChan = make(chan chan float64)
+		c.random.Run()
+		c.started = true
+	}
+
+	var wg sync.WaitGroup
+	ants := make([]Ant, 0, c.Config.AntCount)
+
+	// add ants to graph via the start edge into the home node
+	for i := 0; i < c.Config.AntCount; i++ {
+		ant := c.newAnt(&wg, nil)
+		wg.Add(1)
+		atomic.AddInt32(&c.inFlight, 1)
+		select {
+		case c.Graph.StartEdge.Path <- ant:
+			ants = append(ants, ant)
+		case <-ctx.Done():
+			wg.Done()
+			atomic.AddInt32(&c.inFlight, -1)
+			c.abandon(&wg)
+			return ctx.Err()
+		}
+	}
+
+	// wg completes once all ants are done moving
+	if ctx.Done() == nil {
+		wg.Wait()
+	} else {
+		finished := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(finished)
+		}()
+		select {
+		case <-finished:
+		case <-ctx.Done():
+			c.abandon(&wg)
+			<-finished
+			return ctx.Err()
+		}
+	}
+
+	c.update(ants)
+	return nil
+}
+
+// abandon stops the colony and gives up on the ants still moving through the
+// graph, marking them done in wg.
+func (c *Colony) abandon(wg *sync.WaitGroup) {
+	// no ant moves once the graph has stopped
+	c.Stop()
+	for n := atomic.SwapInt32(&c.inFlight, 0); n > 0; n-- {
+		wg.Done()
+	}
+}
+
+// stepDeterministic runs a single iteration, moving each ant from home to
+// goal in turn, each with its own source of randomness. If ctx is done
+// before every ant is done moving, the iteration is abandoned and ctx.Err()
+// returned.
+func (c *Colony) stepDeterministic(ctx context.Context) error {
+	var wg sync.WaitGroup
+	ants := make([]Ant, 0, c.Config.AntCount)
+
+	for i := 0; i < c.Config.AntCount; i++ {
+		ant := c.newAnt(&wg, rand.New(rand.NewSource(c.random.rand.Int63())))
+		wg.Add(1)
+		atomic.AddInt32(&c.inFlight, 1)
+		node := c.Graph.Nodes[c.Graph.HomeIdx]
+		for {
+			if err := ctx.Err(); err != nil {
+				atomic.StoreInt32(&c.inFlight, 0)
+				return err
+			}
+			next, atGoal := node.Move(ant)
+			if atGoal {
+				break
+			}
+			node = c.Graph.Nodes[next.EndNodeId]
+		}
+		ants = append(ants, ant)
+	}
+
+	c.update(ants)
+	return nil
+}
+
+// update lays down pheromone on the graph based on the paths ants took in an
+// iteration and evaporates pheromone from it. Ants which failed to reach the
+// goal are counted and otherwise ignored.
+func (c *Colony) update(all []Ant) {
+	first := c.Best.Path == nil
+	ants := make([]Ant, 0, len(all))
+	for _, ant := range all {
+		if !ant.Failed() {
+			ants = append(ants, ant)
+			c.Best.Update(c.Graph, ant.Path())
+		}
+	}
+	failed := len(all) - len(ants)
+	c.Failed += failed
+
+	switch c.Config.Ant {
+	case AntACS:
+		c.acsUpdate(first)
+	case AntMMAS:
+		c.mmas.Update(c.Graph, ants)
+	default:
+		// update pheromone based on the ants' paths
+		c.Config.Deposit.Deposit(c.Graph, ants)
+		c.Graph.Dissipate()
+	}
+	c.Iteration++
+	if c.Schedule != nil {
+		c.Schedule.observe(c.Graph, c.Iteration)
+	}
+
+	if c.OnIteration != nil {
+		c.OnIteration(newIterationStats(c, ants, failed))
+	}
+}
+
+// acsUpdate applies the Ant Colony System global update, moving the
+// pheromone on each edge of the best path found so far towards
+// DepositAmt / cost at the rate the graph decays. Once the colony first finds
+// a path, pheromone on every edge is reset to DepositAmt / (n * cost) for a
+// graph of n nodes, which becomes the least pheromone allowed on an edge and
+// the amount ants' local updates move edges towards.
+func (c *Colony) acsUpdate(first bool) {
+	if c.Best.Path == nil {
+		return
+	}
+	q := c.Config.DepositAmt / c.Best.Cost
+	if first {
+		tau0 := q / float64(len(c.Graph.Nodes))
+		c.Graph.SetBounds(tau0, 0)
+		c.Graph.Reset(tau0)
+	}
+	c.Graph.ReinforcePath(c.Best.Path, c.Graph.DecayFactor, q)
+}
+
+// Stop stops the go routines started by Step. The colony may be stepped or
+// run again after it is stopped.
+func (c *Colony) Stop() {
+	if !c.started {
+		return
+	}
+	c.Graph.Stop()
+	c.random.Stop()
+	c.started = false
+}
+
+// newAnt creates an ant of the colony's type at the home node, limited to
+// c.Config.MaxSteps steps. If r is not
+// nil, the ant uses it as its source of randomness rather than the colony's
+// shared RandomSource.
+func (c *Colony) newAnt(wg *sync.WaitGroup, r *rand.Rand) Ant {
+	cfg := c.Config
+	home := c.Graph.HomeIdx
+	switch cfg.Ant {
+	case AntAS, AntMMAS:
+		ant := NewASAnt(home, cfg.DepositAmt, cfg.Alpha, cfg.Beta, c.random.RequestChan, wg)
+		ant.Rand, ant.MaxSteps, ant.OnDone = r, cfg.MaxSteps, c.antDone
+		return ant
+	case AntACS:
+		ant := NewACSAnt(home, cfg.DepositAmt, cfg.Alpha, cfg.Beta, cfg.Q0, cfg.Xi, c.Graph.TauMin, c.random.RequestChan, wg)
+		ant.Rand, ant.MaxSteps, ant.OnDone, ant.Graph = r, cfg.MaxSteps, c.antDone, c.Graph
+		return ant
+	}
+	ant := NewSimpleAnt(home, cfg.DepositAmt, c.random.RequestChan, wg)
+	ant.Rand, ant.MaxSteps, ant.OnDone = r, cfg.MaxSteps, c.antDone
+	return ant
+}
+
+// antDone is called by each ant when it is done moving.
+func (c *Colony) antDone() {
+	atomic.AddInt32(&c.inFlight, -1)
+}
+
+// InFlight returns the number of ants currently moving through the graph. It
+// is safe to call while the colony is running.
+func (c *Colony) InFlight() int {
+	return int(atomic.LoadInt32(&c.inFlight))
+}
+
+// RandomSource provides a shared source of randomness for ants via requests
+// (chan float64s) sent to its RequestChan.
+type RandomSource struct {
+	RequestChan chan chan float64
+	rand        *rand.Rand
+}
+
+// Run initializes a RandomSource to run and wait for requests on its RequestChan.
+func (r *RandomSource) Run() {
+	go func(requests chan chan float64) {
+		for respChan := range requests {
+			respChan <- r.rand.Float64()
+		}
+	}(r.RequestChan)
+}
+
+// Stop closes the RandomSource's RequestChan, stopping the go routine started
+// by Run. No more requests may be sent once it is stopped.
+func (r *RandomSource) Stop() {
+	close(r.RequestChan)
+}
+
+// countingSource is a rand.Source which counts the values drawn from it, so
+// that its state can be restored by seeding a new source with the same seed
+// and drawing the same number of values.
+type countingSource struct {
+	src   rand.Source
+	draws uint64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed)}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
+
+// skip draws values until n have been drawn from the source.
+func (s *countingSource) skip(n uint64) {
+	for s.draws < n {
+		s.Int63()
+	}
+}
@@ -0,0 +1,303 @@
+package acogo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestColonyRun ensures that a Colony runs every iteration and finds a path
+// from home to goal.
+func TestColonyRun(t *testing.T) {
+	for _, ant := range []string{AntSimple, AntAS, AntACS, AntMMAS} {
+		cfg := DefaultConfig()
+		cfg.Ant = ant
+		cfg.Iterations = 20
+
+		g := NewGraph(4, 0, 15, 0.3)
+		colony, err := NewColony(g, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := colony.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Iterations != 20 {
+			t.Error(fmt.Sprintf("%v: expected 20 iterations but ran %v", ant, result.Iterations))
+		}
+		path := result.BestPath
+		if len(path) < 4 || path[0] != 0 || path[len(path)-1] != 15 {
+			t.Error(fmt.Sprintf("%v: expected a path from 0 to 15 of at least 4 nodes but got %v", ant, path))
+		}
+		if result.BestCost != g.PathCost(path) {
+			t.Error(fmt.Sprintf("%v: expected best cost %v but was %v", ant, g.PathCost(path), result.BestCost))
+		}
+		if n := colony.InFlight(); n != 0 {
+			t.Error(fmt.Sprintf("%v: expected no ants in flight after run but found %v", ant, n))
+		}
+	}
+}
+
+// TestColonyCancel ensures that Run stops when its context is done.
+func TestColonyCancel(t *testing.T) {
+	colony, err := NewColony(NewGraph(4, 0, 15, 0.3), DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := colony.Run(ctx)
+	if err != context.Canceled {
+		t.Error(fmt.Sprintf("expected context.Canceled but got %v", err))
+	}
+	if result.Iterations != 0 {
+		t.Error(fmt.Sprintf("expected 0 iterations but ran %v", result.Iterations))
+	}
+}
+
+// TestNewColonyErrors ensures that invalid configurations are rejected.
+func TestNewColonyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		decay float64
+		set   func(*Config)
+	}{
+		{"unknown ant type", 0.3, func(c *Config) { c.Ant = "unknown" }},
+		{"colony without ants", 0.3, func(c *Config) { c.AntCount = 0 }},
+		{"negative alpha", 0.3, func(c *Config) { c.Ant, c.Alpha = AntAS, -1 }},
+		{"negative beta", 0.3, func(c *Config) { c.Ant, c.Beta = AntACS, -2 }},
+		{"acs q0 below 0", 0.3, func(c *Config) { c.Ant, c.Q0 = AntACS, -0.1 }},
+		{"acs q0 above 1", 0.3, func(c *Config) { c.Ant, c.Q0 = AntACS, 5 }},
+		{"acs xi below 0", 0.3, func(c *Config) { c.Ant, c.Xi = AntACS, -0.1 }},
+		{"acs xi above 1", 0.3, func(c *Config) { c.Ant, c.Xi = AntACS, 2 }},
+		{"acs decay of 0", 0, func(c *Config) { c.Ant = AntACS }},
+		{"acs decay above 1", 1.5, func(c *Config) { c.Ant = AntACS }},
+		{"mmas decay of 0", 0, func(c *Config) { c.Ant = AntMMAS }},
+		{"mmas decay above 1", 1.5, func(c *Config) { c.Ant = AntMMAS }},
+		{"mmas pbest of 0", 0.3, func(c *Config) { c.Ant, c.PBest = AntMMAS, 0 }},
+		{"mmas pbest of 1", 0.3, func(c *Config) { c.Ant, c.PBest = AntMMAS, 1 }},
+	}
+	for _, test := range tests {
+		cfg := DefaultConfig()
+		test.set(&cfg)
+		if _, err := NewColony(NewGraph(4, 0, 15, test.decay), cfg); err == nil {
+			t.Error(fmt.Sprintf("expected error for %v", test.name))
+		}
+	}
+}
+
+// TestColonyStop ensures that every go routine started by a Colony exits
+// once it has run.
+func TestColonyStop(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	cfg := DefaultConfig()
+	cfg.Iterations = 5
+	colony, err := NewColony(NewGraph(6, 0, 35, 0.3), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	colony.Step()
+	if n := runtime.NumGoroutine(); n <= baseline {
+		t.Fatal(fmt.Sprintf("expected go routines to be running but found %v, baseline %v", n, baseline))
+	}
+	if _, err := colony.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// exited go routines may take a moment to be cleaned up
+	n := runtime.NumGoroutine()
+	for i := 0; i < 100 && n > baseline; i++ {
+		time.Sleep(10 * time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	if n > baseline {
+		t.Error(fmt.Sprintf("expected %v go routines after run but found %v", baseline, n))
+	}
+}
+
+// TestColonyDeterministic ensures that deterministic colonies with the same
+// seed produce the same graph.
+func TestColonyDeterministic(t *testing.T) {
+	for _, ant := range []string{AntSimple, AntACS, AntMMAS} {
+		cfg := DefaultConfig()
+		cfg.Ant = ant
+		cfg.Iterations = 20
+		cfg.Seed = 42
+		cfg.Deterministic = true
+
+		var dots [3]string
+		for i := range dots {
+			if i == 2 {
+				cfg.Seed = 43
+			}
+			g := NewGraph(5, 0, 24, 0.3)
+			colony, err := NewColony(g, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := colony.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			dots[i] = ToDot(g, DefaultDotOptions()).String()
+		}
+
+		if dots[0] != dots[1] {
+			t.Error(fmt.Sprintf("%v: expected runs with the same seed to produce the same graph", ant))
+		}
+		if dots[0] == dots[2] {
+			t.Error(fmt.Sprintf("%v: expected runs with different seeds to produce different graphs", ant))
+		}
+	}
+}
+
+// TestColonyMaxSteps ensures that ants which exceed MaxSteps give up, are
+// counted as failed and do not deposit pheromone.
+func TestColonyMaxSteps(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Iterations = 1
+	cfg.MaxSteps = 2
+	cfg.Deterministic = true
+
+	// the goal is 5 steps from home, so every ant gives up
+	g := NewGraph(6, 0, 30, 0.0)
+	colony, err := NewColony(g, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := colony.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.FailedAnts != cfg.AntCount {
+		t.Error(fmt.Sprintf("expected %v failed ants but got %v", cfg.AntCount, result.FailedAnts))
+	}
+	if result.BestPath != nil {
+		t.Error(fmt.Sprintf("expected no best path but got %v", result.BestPath))
+	}
+	validateBounds(g, InitialPheromone, InitialPheromone, t)
+}
+
+// trapGraph has a short path from h to g and a cycle between a and b from
+// which the goal cannot be reached.
+const trapGraph = "digraph { h [role=home]; g [role=goal]; h -> g; h -> a; a -> b; b -> a }"
+
+// TestColonyMaxStepsTrap ensures that concurrent ants circling a cycle they
+// cannot leave give up after MaxSteps steps rather than waiting on each other
+// forever.
+func TestColonyMaxStepsTrap(t *testing.T) {
+	g, err := ReadDot([]byte(trapGraph), 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.AntCount, cfg.Iterations, cfg.MaxSteps = 60, 5, 50
+	colony, err := NewColony(g, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finished := make(chan *Result)
+	go func() {
+		result, err := colony.Run(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		finished <- result
+	}()
+	select {
+	case result := <-finished:
+		if result.Iterations != cfg.Iterations || result.FailedAnts == 0 {
+			t.Error(fmt.Sprintf("expected %v iterations with failed ants but got %+v", cfg.Iterations, result))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal(fmt.Sprintf("colony did not finish, %v ants still moving", colony.InFlight()))
+	}
+}
+
+// TestColonyCancelIteration ensures that cancelling a colony whose ants
+// circle a trap forever abandons the iteration in progress and stops every go
+// routine.
+func TestColonyCancelIteration(t *testing.T) {
+	for _, deterministic := range []bool{true, false} {
+		baseline := runtime.NumGoroutine()
+
+		g, err := ReadDot([]byte(trapGraph), 0.3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := DefaultConfig()
+		cfg.AntCount, cfg.MaxSteps, cfg.Seed, cfg.Deterministic = 60, math.MaxInt32, 2, deterministic
+		colony, err := NewColony(g, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		finished := make(chan *Result)
+		go func() {
+			result, err := colony.Run(ctx)
+			if err != context.DeadlineExceeded {
+				t.Error(fmt.Sprintf("deterministic %v: expected %v but got %v", deterministic, context.DeadlineExceeded, err))
+			}
+			finished <- result
+		}()
+		select {
+		case result := <-finished:
+			if result.Iterations != 0 {
+				t.Error(fmt.Sprintf("deterministic %v: expected the iteration to be abandoned but got %v iterations", deterministic, result.Iterations))
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal(fmt.Sprintf("deterministic %v: colony did not stop, %v ants still moving", deterministic, colony.InFlight()))
+		}
+		if n := colony.InFlight(); n != 0 {
+			t.Error(fmt.Sprintf("deterministic %v: expected no ants moving but found %v", deterministic, n))
+		}
+
+		// exited go routines may take a moment to be cleaned up
+		n := runtime.NumGoroutine()
+		for i := 0; i < 100 && n > baseline; i++ {
+			time.Sleep(10 * time.Millisecond)
+			n = runtime.NumGoroutine()
+		}
+		if n > baseline {
+			t.Error(fmt.Sprintf("deterministic %v: expected %v go routines after run but found %v", deterministic, baseline, n))
+		}
+	}
+}
+
+// TestColonyDeadEnd ensures that ants reaching a node without outgoing edges
+// are counted as failed and do not deposit pheromone.
+func TestColonyDeadEnd(t *testing.T) {
+	for _, deterministic := range []bool{true, false} {
+		g, err := ReadDot([]byte("digraph { a [role=home]; c [role=goal]; a -> b; a -> c }"), 0.0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := DefaultConfig()
+		cfg.Iterations, cfg.MaxSteps, cfg.Seed, cfg.Deterministic = 5, 50, 1, deterministic
+		colony, err := NewColony(g, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := colony.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.FailedAnts == 0 || result.FailedAnts == cfg.Iterations*cfg.AntCount {
+			t.Error(fmt.Sprintf("deterministic %v: expected some but not all ants to fail, got %v", deterministic, result.FailedAnts))
+		}
+		if p := g.NodeByName("b").InEdges[0].Pheromone(); p != InitialPheromone {
+			t.Error(fmt.Sprintf("deterministic %v: expected no pheromone deposited on the dead end, got %v", deterministic, p))
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package acogo
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// ColorMap maps values between 0 and 1 onto colors by interpolating between
+// evenly spaced color stops, the first at 0 and the last at 1.
+type ColorMap []color.RGBA
+
+// ColorMaps are the color maps ToDot can draw pheromone with. alpha, blues,
+// heat and viridis are sequential; coolwarm and purplegreen are diverging
+// around the middle of the scale.
+var ColorMaps = map[string]ColorMap{
+	"alpha":       {{0x10, 0x4E, 0x8B, 0x0A}, {0x10, 0x4E, 0x8B, 0xFF}},
+	"blues":       {{0xDE, 0xEB, 0xF7, 0xFF}, {0x6B, 0xAE, 0xD6, 0xFF}, {0x08, 0x30, 0x6B, 0xFF}},
+	"heat":        {{0xFF, 0xFF, 0xB2, 0xFF}, {0xFD, 0x8D, 0x3C, 0xFF}, {0xBD, 0x00, 0x26, 0xFF}},
+	"viridis":     {{0x44, 0x01, 0x54, 0xFF}, {0x3B, 0x52, 0x8B, 0xFF}, {0x21, 0x90, 0x8C, 0xFF}, {0x5D, 0xC9, 0x63, 0xFF}, {0xFD, 0xE7, 0x25, 0xFF}},
+	"coolwarm":    {{0x3B, 0x4C, 0xC0, 0xFF}, {0xDD, 0xDD, 0xDD, 0xFF}, {0xB4, 0x04, 0x26, 0xFF}},
+	"purplegreen": {{0x76, 0x2A, 0x83, 0xFF}, {0xF7, 0xF7, 0xF7, 0xFF}, {0x1B, 0x78, 0x37, 0xFF}},
+}
+
+// At returns the color for v, which is clamped between 0 and 1.
+func (m ColorMap) At(v float64) color.RGBA {
+	if len(m) == 1 {
+		return m[0]
+	}
+	v = math.Max(0, math.Min(1, v))
+
+	// find the stops either side of v and interpolate between them
+	pos := v * float64(len(m)-1)
+	i := int(pos)
+	if i == len(m)-1 {
+		return m[i]
+	}
+	f := pos - float64(i)
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Floor(float64(a) + f*(float64(b)-float64(a)) + 0.5))
+	}
+	return color.RGBA{lerp(m[i].R, m[i+1].R), lerp(m[i].G, m[i+1].G), lerp(m[i].B, m[i+1].B), lerp(m[i].A, m[i+1].A)}
+}
+
+// dotColor formats c as a quoted DOT "#RRGGBBAA" color.
+func dotColor(c color.RGBA) string {
+	return fmt.Sprintf("\"#%02X%02X%02X%02X\"", c.R, c.G, c.B, c.A)
+}
@@ -0,0 +1,158 @@
+package acogo
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Deposit is an interface for the ways ants lay down pheromone on the edges
+// of a Graph once they have all reached the goal.
+type Deposit interface {
+	// Deposit lays down pheromone on g from the paths taken by ants.
+	Deposit(g *Graph, ants []Ant)
+}
+
+// NewDeposit returns the Deposit named by name, one of fixed, quality, rank
+// or elitist. q is the pheromone deposited per unit of path cost, elite the
+// weight of the elitist deposit and rankAnts the weight of the best path in
+// rank deposits. NewDeposit returns an error if rankAnts is less than 1 for
+// rank deposits or elite is negative for elitist deposits.
+func NewDeposit(name string, q, elite float64, rankAnts int) (Deposit, error) {
+	switch name {
+	case "fixed":
+		return FixedDeposit{}, nil
+	case "quality":
+		return QualityDeposit{Q: q}, nil
+	case "rank":
+		if rankAnts < 1 {
+			return nil, fmt.Errorf("rank ants must be at least 1, got %d", rankAnts)
+		}
+		return &RankDeposit{Q: q, W: rankAnts}, nil
+	case "elitist":
+		if elite < 0 {
+			return nil, fmt.Errorf("elite must not be negative, got %v", elite)
+		}
+		return &ElitistDeposit{Q: q, E: elite}, nil
+	}
+	return nil, fmt.Errorf("unknown deposit %q", name)
+}
+
+// FixedDeposit has each ant lay down its own fixed amount of pheromone on
+// its path.
+type FixedDeposit struct{}
+
+// Deposit calls MarkPath on each ant.
+func (FixedDeposit) Deposit(g *Graph, ants []Ant) {
+	for _, ant := range ants {
+		ant.MarkPath(g)
+	}
+}
+
+// QualityDeposit has each ant lay down Q/cost pheromone on its path, so ants
+// which took shorter paths lay down more pheromone.
+type QualityDeposit struct {
+	Q float64
+}
+
+// Deposit lays down d.Q/cost pheromone on the path of each ant.
+func (d QualityDeposit) Deposit(g *Graph, ants []Ant) {
+	for _, ant := range ants {
+		markQuality(g, ant.Path(), d.Q)
+	}
+}
+
+// ElitistDeposit has each ant lay down Q/cost pheromone on its path, and also
+// lays down E*Q/cost pheromone on the best path found so far.
+type ElitistDeposit struct {
+	Q float64
+	E float64
+	// Best path found so far
+	Best Best
+}
+
+// Deposit lays down d.Q/cost pheromone on the path of each ant and
+// d.E*d.Q/cost on the best path found so far.
+func (d *ElitistDeposit) Deposit(g *Graph, ants []Ant) {
+	for _, ant := range ants {
+		path := ant.Path()
+		d.Best.Update(g, path)
+		markQuality(g, path, d.Q)
+	}
+	markQuality(g, d.Best.Path, d.E*d.Q)
+}
+
+// RankDeposit has only the W-1 ants with the lowest cost paths lay down
+// pheromone, weighted by their rank, along with the best path found so far.
+// The ant ranked r lays down (W-r)*Q/cost pheromone and the best path found
+// so far receives W*Q/cost.
+type RankDeposit struct {
+	Q float64
+	W int
+	// Best path found so far
+	Best Best
+}
+
+// Deposit lays down pheromone on the paths of the best ranked ants and the
+// best path found so far.
+func (d *RankDeposit) Deposit(g *Graph, ants []Ant) {
+	ranked := make(rankedPaths, len(ants))
+	for i, ant := range ants {
+		path := ant.Path()
+		d.Best.Update(g, path)
+		ranked[i] = Best{path, g.PathCost(path)}
+	}
+	sort.Sort(ranked)
+
+	for r := 1; r < d.W && r <= len(ranked); r++ {
+		markQuality(g, ranked[r-1].Path, float64(d.W-r)*d.Q)
+	}
+	markQuality(g, d.Best.Path, float64(d.W)*d.Q)
+}
+
+// markQuality lays down q/cost pheromone on the path.
+func markQuality(g *Graph, path []int, q float64) {
+	if cost := g.PathCost(path); cost > 0 {
+		g.MarkPath(path, q/cost)
+	}
+}
+
+// Best tracks the lowest cost path taken by any ant.
+type Best struct {
+	Path []int
+	Cost float64
+}
+
+// Update replaces the best path with path if it has a lower cost, returning
+// whether it did.
+func (b *Best) Update(g *Graph, path []int) bool {
+	if cost := g.PathCost(path); b.Path == nil || cost < b.Cost {
+		b.Path, b.Cost = path, cost
+		return true
+	}
+	return false
+}
+
+// revalidate updates the best path after g has changed, forgetting it if it
+// no longer leads from home to goal along edges of g and otherwise
+// recomputing its cost.
+func (b *Best) revalidate(g *Graph) {
+	if b.Path == nil {
+		return
+	}
+	valid := b.Path[0] == g.HomeIdx && b.Path[len(b.Path)-1] == g.GoalIdx
+	for i := 1; valid && i < len(b.Path); i++ {
+		valid = g.Nodes[b.Path[i]].InEdge(b.Path[i-1]) != nil
+	}
+	if !valid {
+		*b = Best{}
+		return
+	}
+	b.Cost = g.PathCost(b.Path)
+}
+
+// rankedPaths sorts paths by ascending cost.
+type rankedPaths []Best
+
+func (r rankedPaths) Len() int           { return len(r) }
+func (r rankedPaths) Less(i, j int) bool { return r[i].Cost < r[j].Cost }
+func (r rankedPaths) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
@@ -0,0 +1,69 @@
+package acogo
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestQualityDeposit ensures that ants with shorter paths lay down more
+// pheromone.
+func TestQualityDeposit(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	ants := []Ant{pathAnt{0, 1, 2}, pathAnt{0, 3, 6, 7, 8}}
+
+	QualityDeposit{4.0}.Deposit(g, ants)
+	validatePheromone(g.Nodes[1].InEdge(0), 12.0, t)
+	validatePheromone(g.Nodes[3].InEdge(0), 11.0, t)
+}
+
+// TestElitistDeposit ensures that the best path found so far receives an
+// extra deposit each iteration.
+func TestElitistDeposit(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	d := &ElitistDeposit{Q: 2.0, E: 3.0}
+
+	d.Deposit(g, []Ant{pathAnt{0, 1, 2}})
+	validatePheromone(g.Nodes[1].InEdge(0), 14.0, t)
+
+	d.Deposit(g, []Ant{pathAnt{0, 3, 6, 7, 8}})
+	validatePheromone(g.Nodes[1].InEdge(0), 17.0, t)
+	validatePheromone(g.Nodes[3].InEdge(0), 10.5, t)
+	if d.Best.Cost != 2.0 {
+		t.Error(fmt.Sprintf("expected best cost 2.0 but was %v", d.Best.Cost))
+	}
+}
+
+// TestRankDeposit ensures that only the best ranked ants lay down pheromone,
+// weighted by rank.
+func TestRankDeposit(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	d := &RankDeposit{Q: 6.0, W: 3}
+
+	d.Deposit(g, []Ant{pathAnt{0, 3, 6, 7, 8}, pathAnt{0, 1}, pathAnt{0, 3, 6}, pathAnt{0, 1, 2}})
+	// 0 -> 1 is on the best path (weight 3) and the best ranked path (weight 2)
+	validatePheromone(g.Nodes[1].InEdge(0), 10.0+18.0+12.0, t)
+	// 0 -> 3 is only on the second ranked path, of cost 2
+	validatePheromone(g.Nodes[3].InEdge(0), 13.0, t)
+	// 7 -> 8 is on a path which is not ranked high enough to deposit
+	validatePheromone(g.Nodes[8].InEdge(7), 10.0, t)
+}
+
+// TestNewDepositErrors ensures that unknown deposits and deposits with
+// weights which would remove pheromone are rejected.
+func TestNewDepositErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		elite    float64
+		rankAnts int
+	}{
+		{"none", 5, 6},
+		{"rank", 5, 0},
+		{"rank", 5, -5},
+		{"elitist", -1, 6},
+	}
+	for _, test := range tests {
+		if _, err := NewDeposit(test.name, 1.0, test.elite, test.rankAnts); err == nil {
+			t.Error(fmt.Sprintf("expected error for %v deposit with elite %v and rank ants %v", test.name, test.elite, test.rankAnts))
+		}
+	}
+}
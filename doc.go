@@ -0,0 +1,24 @@
+// Copyright (c) 2014 Joanna Solmon. All rights reserved.
+// Use of this source code is governed by the MIT License found in the LICENSE file.
+
+/*
+Package acogo provides a framework for playing with ant colony optimization.
+
+A Colony runs ants from the home node to the goal node of a Graph, laying down
+pheromone on the edges they travel, e.g.
+
+	graph := acogo.NewGraph(6, 0, 35, 0.3)
+	colony, err := acogo.NewColony(graph, acogo.DefaultConfig())
+	if err != nil {
+		return err
+	}
+	result, err := colony.Run(ctx)
+
+Graphs may also be generated on other lattices with NewTopologyGraph, built
+from ASCII maps of grid worlds with ReadMapFile, read from DOT files with
+ReadDotFile, and written back out with ToDot. A Schedule of events blocks,
+unblocks and reweights edges or moves the goal between iterations, recording
+how long the colony takes to re-converge after each. The acogo command in
+cmd/acogo runs a colony from the command line.
+*/
+package acogo
@@ -0,0 +1,190 @@
+package acogo
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"code.google.com/p/gographviz"
+)
+
+// ReadDotFile reads the DOT file at path and builds an acogo Graph from it.
+// See ReadDot for the attributes acogo understands.
+func ReadDotFile(path string, decayFactor float64) (*Graph, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ReadDot(buf, decayFactor)
+}
+
+// ReadDot parses a DOT language graph and builds an acogo Graph from it.
+// Nodes are numbered in the order they first appear in the DOT source. The
+// home and goal nodes are marked with a role attribute, e.g.
+//
+//	a [role=home]
+//	z [role=goal]
+//
+// Nodes keep their position if they have a pos attribute. Edges may set
+// their initial pheromone and cost with the pheromone and cost attributes,
+// which must be positive. Pheromone is raised to at least MinPheromone.
+// Edges in undirected graphs are added in both directions.
+func ReadDot(buf []byte, decayFactor float64) (*Graph, error) {
+	g, err := readDot(buf, decayFactor)
+	if err != nil {
+		return nil, err
+	}
+	if g.HomeIdx == -1 {
+		return nil, errors.New("dot graph has no node with role=home")
+	}
+	if g.GoalIdx == -1 {
+		return nil, errors.New("dot graph has no node with role=goal")
+	}
+	return g, nil
+}
+
+// readDot reads a DOT graph as ReadDot does, except that the graph's HomeIdx
+// and GoalIdx are -1 if no node has the home or goal role.
+func readDot(buf []byte, decayFactor float64) (*Graph, error) {
+	tree, err := gographviz.Parse(buf)
+	if err != nil {
+		return nil, err
+	}
+	gv := gographviz.NewGraph()
+	gographviz.Analyse(tree, gv)
+
+	// map DOT node names to acogo node ids and find home and goal, skipping
+	// the legend drawn by ToDot
+	legend := gv.Relations.ParentToChildren[legendName]
+	ids := make(map[string]int, len(gv.Nodes.Nodes))
+	var names []string
+	var dotAttrs []gographviz.Attrs
+	homeNode, goalNode := -1, -1
+	for _, n := range gv.Nodes.Nodes {
+		if legend[n.Name] {
+			continue
+		}
+		id := len(names)
+		ids[n.Name] = id
+		names = append(names, unquote(n.Name))
+		dotAttrs = append(dotAttrs, n.Attrs)
+
+		switch role := unquote(n.Attrs["role"]); role {
+		case "home":
+			if homeNode != -1 {
+				return nil, fmt.Errorf("dot graph has more than one home node: %v and %v", names[homeNode], names[id])
+			}
+			homeNode = id
+		case "goal":
+			if goalNode != -1 {
+				return nil, fmt.Errorf("dot graph has more than one goal node: %v and %v", names[goalNode], names[id])
+			}
+			goalNode = id
+		case "":
+		default:
+			return nil, fmt.Errorf("node %v has unknown role %q", names[id], role)
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("dot graph has no nodes")
+	}
+
+	edges := newEdgeList(len(names))
+	for _, de := range gv.Edges.Edges {
+		if legend[de.Src] || legend[de.Dst] {
+			continue
+		}
+		start, end := ids[de.Src], ids[de.Dst]
+		if err := addDotEdge(edges, names, start, end, de.Attrs); err != nil {
+			return nil, err
+		}
+		if !de.Dir {
+			if err := addDotEdge(edges, names, end, start, de.Attrs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	g := newNamedGraph(names, edges.out, homeNode, goalNode, decayFactor)
+	for _, n := range g.Nodes {
+		for _, e := range n.InEdges {
+			e.pheromone = g.bound(e.pheromone)
+		}
+	}
+	for id, n := range g.Nodes {
+		if v, ok := dotAttrs[id]["pos"]; ok {
+			if err := setPos(n, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return g, nil
+}
+
+// addDotEdge adds the edge from start to end to edges, setting its pheromone
+// and cost from the DOT attributes if present. Both must be finite and
+// positive, and errors name the edge's nodes from names.
+func addDotEdge(edges *edgeList, names []string, start, end int, attrs gographviz.Attrs) error {
+	e := NewEdge(start, end)
+	if v, ok := attrs["pheromone"]; ok {
+		f, err := parsePositive(v)
+		if err != nil {
+			return fmt.Errorf("edge %v -> %v: invalid pheromone %v", names[start], names[end], v)
+		}
+		e.pheromone = f
+	}
+	if v, ok := attrs["cost"]; ok {
+		f, err := parsePositive(v)
+		if err != nil {
+			return fmt.Errorf("edge %v -> %v: invalid cost %v", names[start], names[end], v)
+		}
+		e.Cost = f
+	}
+	edges.add(e)
+	return nil
+}
+
+// parsePositive parses a DOT attribute holding a finite, positive number.
+func parsePositive(v string) (float64, error) {
+	f, err := strconv.ParseFloat(unquote(v), 64)
+	if err != nil {
+		return 0, err
+	}
+	if f <= 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("%v is not finite and positive", f)
+	}
+	return f, nil
+}
+
+// setPos sets the position of n from a DOT pos attribute such as "1,2" or
+// "1,2!".
+func setPos(n *Node, v string) error {
+	coords := strings.Split(strings.TrimSuffix(unquote(v), "!"), ",")
+	if len(coords) < 2 {
+		return fmt.Errorf("node %v: invalid pos %v", n.Name, v)
+	}
+	x, err := strconv.ParseFloat(strings.TrimSpace(coords[0]), 64)
+	if err != nil {
+		return fmt.Errorf("node %v: invalid pos %v", n.Name, v)
+	}
+	y, err := strconv.ParseFloat(strings.TrimSpace(coords[1]), 64)
+	if err != nil {
+		return fmt.Errorf("node %v: invalid pos %v", n.Name, v)
+	}
+	n.X, n.Y, n.HasPos = x, y, true
+	return nil
+}
+
+// unquote strips the surrounding quotes from a DOT ID if it is a quoted
+// string.
+func unquote(s string) string {
+	if strings.HasPrefix(s, "\"") {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
@@ -0,0 +1,158 @@
+package acogo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestReadDot ensures that ReadDot builds nodes and edges from a DOT graph,
+// marking home and goal nodes and setting edge pheromone and cost.
+func TestReadDot(t *testing.T) {
+	g, err := ReadDot([]byte(`digraph {
+		a [role=home]
+		d [role=goal]
+		a -> b -> d
+		a -> c -> d [pheromone=5.0, cost=2.5]
+	}`), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.Nodes) != 4 {
+		t.Fatal(fmt.Sprintf("expected 4 nodes but found %v\n", len(g.Nodes)))
+	}
+	// nodes are numbered in the order they first appear: a, d, b, c
+	if g.HomeIdx != 0 || g.GoalIdx != 1 {
+		t.Error(fmt.Sprintf("expected home 0 and goal 1 but got %v and %v", g.HomeIdx, g.GoalIdx))
+	}
+	if len(g.Nodes[0].OutEdges) != 2 || len(g.Nodes[1].InEdges) != 2 {
+		t.Error("expected two edges out of home and two into goal")
+	}
+	for _, e := range g.Nodes[1].InEdges {
+		switch g.Nodes[e.StartNodeId].Name {
+		case "b":
+			if e.Pheromone() != 10.0 || e.Cost != 1.0 {
+				t.Error(fmt.Sprintf("expected default pheromone and cost on b -> d but got %v", e))
+			}
+		case "c":
+			if e.Pheromone() != 5.0 || e.Cost != 2.5 {
+				t.Error(fmt.Sprintf("expected pheromone 5.0 and cost 2.5 on c -> d but got %v, %v", e, e.Cost))
+			}
+		}
+	}
+}
+
+// TestReadDotUndirected ensures that undirected edges are added in both
+// directions.
+func TestReadDotUndirected(t *testing.T) {
+	g, err := ReadDot([]byte(`graph { a [role=home]; b [role=goal]; a -- b }`), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateNode(g.Nodes[0], []int{1}, Home, t)
+	validateNode(g.Nodes[1], []int{0}, Goal, t)
+}
+
+// TestReadDotErrors ensures that graphs without a home or goal, or with edges
+// without finite, positive pheromone and cost, are rejected.
+func TestReadDotErrors(t *testing.T) {
+	for _, src := range []string{
+		`digraph { a -> b }`,
+		`digraph { a [role=home]; a -> b }`,
+		`digraph { a [role=home]; b [role=home]; c [role=goal] }`,
+		`digraph { a [role=home]; b [role=goal]; a -> b [cost=0] }`,
+		`digraph { a [role=home]; b [role=goal]; a -> b [cost=NaN] }`,
+		`digraph { a [role=home]; b [role=goal]; a -> b [pheromone=0] }`,
+		`digraph { a [role=home]; b [role=goal]; a -> b [pheromone=-1] }`,
+		`digraph { a [role=home]; b [role=goal]; a -> b [pheromone=NaN] }`,
+		`digraph { a [role=home]; b [role=goal]; a -> b [pheromone="+Inf"] }`,
+	} {
+		if _, err := ReadDot([]byte(src), 0.5); err == nil {
+			t.Error(fmt.Sprintf("expected error reading %v", src))
+		}
+	}
+}
+
+// TestReadDotEdgeErrors ensures that errors for invalid edges name the edge's
+// nodes.
+func TestReadDotEdgeErrors(t *testing.T) {
+	for _, attr := range []string{"cost=0", "pheromone=-1"} {
+		src := `digraph { home [role=home]; goal [role=goal]; home -> goal [` + attr + `] }`
+		if _, err := ReadDot([]byte(src), 0.5); err == nil || !strings.Contains(err.Error(), "edge home -> goal") {
+			t.Error(fmt.Sprintf("expected error naming edge home -> goal reading %v but got %v", attr, err))
+		}
+	}
+}
+
+// TestReadDotBounds ensures that pheromone read from a DOT graph is kept
+// within the graph's bounds.
+func TestReadDotBounds(t *testing.T) {
+	g, err := ReadDot([]byte(`digraph { a [role=home]; b [role=goal]; a -> b [pheromone=0.001] }`), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateBounds(g, MinPheromone, MinPheromone, t)
+}
+
+// TestReadDotPos ensures that nodes keep their positions and that graphs
+// written by ToDot keep their layout when read back in.
+func TestReadDotPos(t *testing.T) {
+	g, err := ReadDot([]byte(`digraph { a [role=home, pos="1.5,2!"]; b [role=goal]; a -> b }`), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := g.Nodes[0]; !a.HasPos || a.X != 1.5 || a.Y != 2 {
+		t.Error(fmt.Sprintf("expected a at 1.5,2 but got %v,%v", a.X, a.Y))
+	}
+	if g.Nodes[1].HasPos {
+		t.Error("expected b to have no position")
+	}
+
+	if _, err := ReadDot([]byte(`digraph { a [role=home, pos="1"]; b [role=goal]; a -> b }`), 0.5); err == nil {
+		t.Error("expected error for invalid pos")
+	}
+
+	grid := NewGraph(3, 0, 8, 0.5)
+	g, err = ReadDot([]byte(ToDot(grid, DefaultDotOptions()).String()), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, n := range g.Nodes {
+		id, _ := strconv.Atoi(n.Name)
+		if expected := grid.Nodes[id]; !n.HasPos || n.X != expected.X || n.Y != expected.Y {
+			t.Error(fmt.Sprintf("expected node %v at %v,%v but got %v,%v", i, expected.X, expected.Y, n.X, n.Y))
+		}
+	}
+}
+
+// TestReadDotRoundTrip ensures that ReadDot reads the output of ToDot back as
+// the same graph with the same pheromone and cost on each edge.
+func TestReadDotRoundTrip(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.3)
+	g.MarkPath([]int{0, 4, 8}, 1.0/3)
+	read, err := ReadDot([]byte(ToDot(g, DefaultDotOptions()).String()), 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nodes may be numbered differently, so match them by name
+	edges := ToJSON(g).Edges
+	if n := len(ToJSON(read).Edges); n != len(edges) {
+		t.Fatal(fmt.Sprintf("expected %v edges but read %v", len(edges), n))
+	}
+	for _, je := range edges {
+		from, to := read.NodeByName(je.From), read.NodeByName(je.To)
+		if from == nil || to == nil {
+			t.Fatal(fmt.Sprintf("expected to read nodes %v and %v", je.From, je.To))
+		}
+		e := to.InEdge(from.Id)
+		if e == nil || e.Cost != je.Cost || e.Pheromone() != je.Pheromone {
+			t.Error(fmt.Sprintf("expected edge %v -> %v with cost %v and pheromone %v but read %v", je.From, je.To, je.Cost, je.Pheromone, e))
+		}
+	}
+	if home := read.Nodes[read.HomeIdx]; home.Name != "0" || home.X != 0 || home.Y != 2*GridSpacing {
+		t.Error(fmt.Sprintf("expected home node 0 at 0,%v but read %v at %v,%v", 2*GridSpacing, home.Name, home.X, home.Y))
+	}
+}
@@ -1,58 +1,288 @@
-package main
+package acogo
 
 import (
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
+	"unicode"
 
 	"code.google.com/p/gographviz"
 )
 
+// Highlight is a path to draw in a distinct color and width in ToDot.
+type Highlight struct {
+	// List of nodeIds along the path
+	Path []int
+	// DOT color and penwidth of the edges along the path
+	Color    string
+	Penwidth float64
+}
+
+// Highlights for the consensus path and the best path found by any ant.
+var (
+	ConsensusHighlight = Highlight{Color: "#FF8C00", Penwidth: 6.0} // dark orange
+	BestHighlight      = Highlight{Color: "#DC143C", Penwidth: 6.0} // crimson
+)
+
+// Ways ToDot can scale the pheromone on each edge onto its color.
+const (
+	// Linearly between the least and most pheromone on any edge
+	ScaleLinear = "linear"
+	// Logarithmically between the least and most pheromone on any edge
+	ScaleLog = "log"
+	// Linearly between the least pheromone on any edge and the given
+	// percentile, with edges above the percentile drawn in the last color
+	ScalePercentile = "percentile"
+)
+
+// DotOptions holds the options for drawing a Graph with ToDot.
+type DotOptions struct {
+	// How pheromone is scaled onto edge colors, one of ScaleLinear, ScaleLog
+	// or ScalePercentile
+	Scale string
+	// Pheromone drawn in the last color of ColorMap by ScaleLinear and
+	// ScaleLog, or 0 for the most pheromone on any edge
+	Max float64
+	// Percentile of edge pheromone drawn in the last color by
+	// ScalePercentile, between 0 and 100
+	Percentile float64
+	// Colors to draw edges in, from least to most pheromone
+	ColorMap ColorMap
+	// Whether edges are labeled with their pheromone
+	Labels bool
+	// Whether to draw a legend of the color scale
+	Legend bool
+	// Paths to draw in their own colors, later highlights drawn over earlier
+	// ones
+	Highlights []Highlight
+}
+
+// DefaultDotOptions returns the DotOptions used by the acogo command unless
+// told otherwise.
+func DefaultDotOptions() DotOptions {
+	return DotOptions{
+		Scale:      ScaleLinear,
+		Percentile: 95,
+		ColorMap:   ColorMaps["alpha"],
+		Legend:     true,
+	}
+}
+
+// number of entries in the legend and the name of its subgraph, which ReadDot
+// skips when reading ToDot output back in
+const (
+	legendEntries = 5
+	legendName    = "cluster_legend"
+)
+
 // ToDot takes in an acogo Graph and transforms it into a DOT language
-// graph with the darkness of the edge color corresponding to the amount
-// of pheromone on the edge.
-func ToDot(g *Graph, max float64) *gographviz.Graph {
+// graph with the color of each edge corresponding to the amount of pheromone
+// on the edge, scaled and colored as set in opts. Nodes with a position are
+// pinned to it, and if every node has one the graph is laid out with neato
+// so that it keeps its shape, e.g. with neato -n.
+func ToDot(g *Graph, opts DotOptions) *gographviz.Graph {
 	gv := gographviz.NewGraph()
 	gv.SetDir(true)
 
+	// find the top right corner of the graph if every node has a position
+	positioned := len(g.Nodes) > 0
+	var maxX, maxY float64
+	for i, n := range g.Nodes {
+		positioned = positioned && n.HasPos
+		if i == 0 || n.X > maxX {
+			maxX = n.X
+		}
+		if i == 0 || n.Y > maxY {
+			maxY = n.Y
+		}
+	}
+	if positioned {
+		gv.AddAttr(gv.Name, "layout", "neato")
+	}
+
+	// map each highlighted edge to its highlight
+	highlighted := make(map[[2]int]Highlight)
+	for _, h := range opts.Highlights {
+		for i := 1; i < len(h.Path); i++ {
+			highlighted[[2]int{h.Path[i-1], h.Path[i]}] = h
+		}
+	}
+
+	sc := newScale(g, opts)
 	for _, n := range g.Nodes {
-		gv.AddNode(gv.Name, strconv.Itoa(n.Id), nodeAttrs(n))
+		gv.AddNode(gv.Name, dotId(n.Name), nodeAttrs(n))
 		for _, e := range n.InEdges {
-			gv.AddEdge(strconv.Itoa(e.StartNodeId), strconv.Itoa(e.EndNodeId), true, edgeAttrs(e, max))
+			attrs := edgeAttrs(e, sc, opts)
+			if h, ok := highlighted[[2]int{e.StartNodeId, e.EndNodeId}]; ok {
+				attrs["color"] = strconv.Quote(h.Color)
+				attrs["penwidth"] = strconv.FormatFloat(h.Penwidth, 'f', 1, 64)
+			}
+			gv.AddEdge(dotId(g.Nodes[e.StartNodeId].Name), dotId(n.Name), true, attrs)
 		}
 	}
 
+	if opts.Legend {
+		addLegend(gv, sc, opts, maxX+2*GridSpacing, maxY, positioned)
+	}
 	return gv
 }
 
+// addLegend adds a subgraph to gv with a box for each of legendEntries
+// evenly spaced points on the color scale, labeled with their pheromone. If
+// positioned is set, the boxes are placed in a column below x, y.
+func addLegend(gv *gographviz.Graph, sc scale, opts DotOptions, x, y float64, positioned bool) {
+	gv.AddSubGraph(gv.Name, legendName, map[string]string{"label": "\"pheromone\""})
+	for i := 0; i < legendEntries; i++ {
+		v := float64(i) / (legendEntries - 1)
+		name := fmt.Sprintf("legend%d", i)
+		attrs := map[string]string{
+			"shape":     "box",
+			"style":     "filled",
+			"fillcolor": dotColor(opts.ColorMap.At(v)),
+			"label":     strconv.Quote(strconv.FormatFloat(sc.value(v), 'g', 3, 64)),
+		}
+		if positioned {
+			attrs["pos"] = dotPos(x, y-float64(i)*GridSpacing/2)
+		}
+		gv.AddNode(legendName, name, attrs)
+		// invisible edges keep the boxes in order
+		if i > 0 {
+			gv.AddEdge(fmt.Sprintf("legend%d", i-1), name, true, map[string]string{"style": "invis"})
+		}
+	}
+}
+
+// scale maps pheromone between lo and hi onto values between 0 and 1.
+type scale struct {
+	lo, hi float64
+	log    bool
+}
+
+// newScale finds the bounds of the scale set by opts from the pheromone on
+// the edges of g.
+func newScale(g *Graph, opts DotOptions) scale {
+	var pheromones []float64
+	for _, n := range g.Nodes {
+		for _, e := range n.OutEdges {
+			pheromones = append(pheromones, e.Pheromone())
+		}
+	}
+	if len(pheromones) == 0 {
+		return scale{}
+	}
+	sort.Float64s(pheromones)
+
+	sc := scale{lo: pheromones[0], hi: pheromones[len(pheromones)-1]}
+	switch opts.Scale {
+	case ScaleLog:
+		sc.log = true
+		// log scales need a positive lower bound
+		if sc.lo <= 0 {
+			sc.lo = MinPheromone
+		}
+	case ScalePercentile:
+		i := int(opts.Percentile / 100 * float64(len(pheromones)-1))
+		if i < 0 {
+			i = 0
+		} else if i >= len(pheromones) {
+			i = len(pheromones) - 1
+		}
+		sc.hi = pheromones[i]
+		return sc
+	}
+	if opts.Max > 0 {
+		sc.hi = opts.Max
+	}
+	return sc
+}
+
+// at returns where pheromone p falls on the scale, between 0 and 1. If every
+// edge has the same pheromone, it is at the top of the scale.
+func (sc scale) at(p float64) float64 {
+	if sc.hi <= sc.lo {
+		return 1
+	}
+	if sc.log {
+		return (math.Log(math.Max(p, sc.lo)) - math.Log(sc.lo)) / (math.Log(sc.hi) - math.Log(sc.lo))
+	}
+	return (p - sc.lo) / (sc.hi - sc.lo)
+}
+
+// value returns the pheromone at v on the scale, the inverse of at.
+func (sc scale) value(v float64) float64 {
+	if sc.log && sc.hi > sc.lo {
+		return math.Exp(math.Log(sc.lo) + v*(math.Log(sc.hi)-math.Log(sc.lo)))
+	}
+	return sc.lo + v*(sc.hi-sc.lo)
+}
+
+// dotId quotes a node name for use as a DOT ID unless it is already a valid
+// unquoted ID.
+func dotId(name string) string {
+	if name == "" {
+		return "\"\""
+	}
+	numeral, id := true, true
+	for i, r := range name {
+		numeral = numeral && unicode.IsDigit(r)
+		id = id && (r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)))
+	}
+	if numeral || id {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// dotPos formats x, y as a quoted DOT position pinning a node in place.
+func dotPos(x, y float64) string {
+	return fmt.Sprintf("\"%v,%v!\"", x, y)
+}
+
 // nodeAttrs assigns DOT attributes to a node, assigning labels and
-// colors based on whether they are home or goal nodes.
+// colors based on whether they are home or goal nodes. Home and goal nodes
+// are also given a role attribute so the output can be read back by ReadDot.
+// Nodes without edges, such as the walls of a GridMap, are drawn as filled
+// squares.
 func nodeAttrs(n *Node) map[string]string {
-	attrs := make(map[string]string, 2)
+	attrs := make(map[string]string, 4)
+	if n.HasPos {
+		attrs["pos"] = dotPos(n.X, n.Y)
+	}
 
 	switch n.Type {
 	case Home:
 		attrs["color"] = "\"#8B0000\"" // maroon
-		attrs["label"] = fmt.Sprintf("\"%v: HOME\"", strconv.Itoa(n.Id))
+		attrs["label"] = strconv.Quote(fmt.Sprintf("%v: HOME", n.Name))
+		attrs["role"] = "home"
 	case Goal:
 		attrs["color"] = "\"#008000\"" // green
-		attrs["label"] = fmt.Sprintf("\"%v: GOAL\"", strconv.Itoa(n.Id))
+		attrs["label"] = strconv.Quote(fmt.Sprintf("%v: GOAL", n.Name))
+		attrs["role"] = "goal"
 	default: // path nodes
 		attrs["color"] = "\"#D3D3D3\"" // light grey
+		if n.isWall() {
+			attrs["color"] = "\"#404040\"" // dark grey
+			attrs["shape"] = "box"
+			attrs["style"] = "filled"
+		}
 	}
 	return attrs
 }
 
-// edgeAttrs assigns DOT attributes to a node, assigning darker colors
-// to nodes with more pheromone and lighter colors to nodes with less.
-func edgeAttrs(e *Edge, max float64) map[string]string {
-	attrs := make(map[string]string, 3)
+// edgeAttrs assigns DOT attributes to an edge, coloring it by where its
+// pheromone falls on the scale and labeling it with its pheromone if
+// opts.Labels is set. Edges are also given pheromone and cost attributes so
+// the output can be read back by ReadDot.
+func edgeAttrs(e *Edge, sc scale, opts DotOptions) map[string]string {
+	attrs := make(map[string]string, 6)
 	attrs["penwidth"] = "3.0"
 	attrs["arrowType"] = "open"
-
-	// sets a minimum alpha value of 10 so edges with no traffic will still appear in the graph
-	alpha := int(e.pheromone/max*245) + 10
-	color := fmt.Sprintf("\"#104E8B%X\"", alpha)
-	attrs["color"] = color
-
+	attrs["color"] = dotColor(opts.ColorMap.At(sc.at(e.Pheromone())))
+	attrs["pheromone"] = strconv.FormatFloat(e.Pheromone(), 'f', -1, 64)
+	attrs["cost"] = strconv.FormatFloat(e.Cost, 'f', -1, 64)
+	if opts.Labels {
+		attrs["label"] = strconv.Quote(strconv.FormatFloat(e.Pheromone(), 'g', 3, 64))
+	}
 	return attrs
 }
@@ -0,0 +1,104 @@
+package acogo
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestToDotHighlights ensures that highlighted paths are drawn with their
+// color and penwidth.
+func TestToDotHighlights(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	h := BestHighlight
+	h.Path = []int{0, 4, 8}
+	opts := DefaultDotOptions()
+	opts.Legend = false
+	opts.Highlights = []Highlight{h}
+	dot := ToDot(g, opts).String()
+
+	for _, line := range strings.Split(dot, "\n") {
+		highlighted := strings.Contains(line, `"#DC143C"`) && strings.Contains(line, "penwidth=6.0")
+		onPath := strings.Contains(line, "0->4[") || strings.Contains(line, "4->8[")
+		if highlighted != onPath {
+			t.Error(fmt.Sprintf("expected only edges on the path to be highlighted but got %v", line))
+		}
+	}
+}
+
+// TestToDotColors ensures that edge colors are well formed and scaled
+// against the pheromone actually on the graph.
+func TestToDotColors(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	g.MarkPath([]int{0, 4, 8}, 10.0)
+	g.Nodes[1].OutEdges[0].Addpheromone(-10.0)
+
+	opts := DefaultDotOptions()
+	opts.Labels = true
+	dot := ToDot(g, opts).String()
+	for _, expected := range []string{
+		`color="#104E8BFF", label="20"`,  // most pheromone
+		`color="#104E8B0A", label="0.1"`, // least pheromone, alpha below 16
+		`subgraph cluster_legend`,
+		`fillcolor="#104E8BFF", label="20"`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Error(fmt.Sprintf("expected %v in %v", expected, dot))
+		}
+	}
+
+	// the legend is skipped when read back in
+	read, err := ReadDot([]byte(strings.Replace(dot, `label="0: HOME"`, `role=home`, 1)), 0.5)
+	if err == nil && len(read.Nodes) != len(g.Nodes) {
+		t.Error(fmt.Sprintf("expected %v nodes read back but got %v", len(g.Nodes), len(read.Nodes)))
+	} else if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestScale ensures that each scale maps pheromone between 0 and 1 and back.
+func TestScale(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	g.MarkPath([]int{0, 4, 8}, 90.0)
+
+	opts := DefaultDotOptions()
+	for _, test := range []struct {
+		scale      string
+		percentile float64
+		p, v       float64
+	}{
+		{ScaleLinear, 0, 55, 0.5},
+		{ScaleLog, 0, 10 * math.Sqrt(10), 0.5},
+		{ScalePercentile, 50, 20, 1},
+		{ScalePercentile, 100, 55, 0.5},
+	} {
+		opts.Scale, opts.Percentile = test.scale, test.percentile
+		sc := newScale(g, opts)
+		if v := sc.at(test.p); math.Abs(v-test.v) > 1e-9 {
+			t.Error(fmt.Sprintf("%v: expected %v at %v but got %v", test.scale, test.p, test.v, v))
+		}
+		if p := sc.value(sc.at(test.p)); test.v < 1 && math.Abs(p-test.p) > 1e-9 {
+			t.Error(fmt.Sprintf("%v: expected value %v but got %v", test.scale, test.p, p))
+		}
+	}
+}
+
+// TestColorMapAt ensures that ColorMap interpolates between its stops.
+func TestColorMapAt(t *testing.T) {
+	m := ColorMap{{0, 0, 0, 0}, {100, 200, 0, 255}, {0, 0, 0, 255}}
+	for _, test := range []struct {
+		v        float64
+		expected color.RGBA
+	}{
+		{-1, color.RGBA{0, 0, 0, 0}},
+		{0.25, color.RGBA{50, 100, 0, 128}},
+		{0.5, color.RGBA{100, 200, 0, 255}},
+		{1, color.RGBA{0, 0, 0, 255}},
+	} {
+		if c := m.At(test.v); c != test.expected {
+			t.Error(fmt.Sprintf("expected %v at %v but got %v", test.expected, test.v, c))
+		}
+	}
+}
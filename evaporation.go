@@ -0,0 +1,72 @@
+package acogo
+
+import (
+	"fmt"
+)
+
+// Evaporation is an interface for the ways pheromone evaporates from the
+// edges of a Graph after each iteration.
+type Evaporation interface {
+	// Evaporate removes pheromone from the edge e in graph g.
+	Evaporate(g *Graph, e *Edge)
+}
+
+// NewEvaporation returns the Evaporation named by name, one of subtractive,
+// multiplicative or visited. decay is the amount of pheromone subtracted from
+// each edge by subtractive evaporation, or the proportion evaporated by
+// multiplicative and visited evaporation. It returns an error if decay is
+// negative for subtractive evaporation, or not greater than 0 and at most 1
+// for multiplicative and visited evaporation.
+func NewEvaporation(name string, decay float64) (Evaporation, error) {
+	switch name {
+	case "subtractive":
+		if decay < 0 {
+			return nil, fmt.Errorf("decay must not be negative, got %v", decay)
+		}
+		return SubtractiveEvaporation{decay}, nil
+	case "multiplicative", "visited":
+		if decay <= 0 || decay > 1 {
+			return nil, fmt.Errorf("decay must be greater than 0 and at most 1 for %v evaporation, got %v", name, decay)
+		}
+		if name == "multiplicative" {
+			return MultiplicativeEvaporation{decay}, nil
+		}
+		return VisitedEvaporation{MultiplicativeEvaporation{decay}}, nil
+	}
+	return nil, fmt.Errorf("unknown evaporation %q", name)
+}
+
+// SubtractiveEvaporation subtracts a fixed Amount of pheromone from each
+// edge.
+type SubtractiveEvaporation struct {
+	Amount float64
+}
+
+// Evaporate subtracts s.Amount pheromone from the edge.
+func (s SubtractiveEvaporation) Evaporate(g *Graph, e *Edge) {
+	g.addpheromone(e, -1*s.Amount)
+}
+
+// MultiplicativeEvaporation evaporates a proportion Rho of the pheromone on
+// each edge, so tau becomes (1-Rho)*tau.
+type MultiplicativeEvaporation struct {
+	Rho float64
+}
+
+// Evaporate multiplies the pheromone on the edge by 1-m.Rho.
+func (m MultiplicativeEvaporation) Evaporate(g *Graph, e *Edge) {
+	e.updatepheromone(func(p float64) float64 { return g.bound((1 - m.Rho) * p) })
+}
+
+// VisitedEvaporation only evaporates pheromone from edges which an ant has
+// crossed since the last evaporation, using the embedded Evaporation.
+type VisitedEvaporation struct {
+	Evaporation
+}
+
+// Evaporate evaporates pheromone from the edge if an ant has crossed it.
+func (v VisitedEvaporation) Evaporate(g *Graph, e *Edge) {
+	if e.Visited() {
+		v.Evaporation.Evaporate(g, e)
+	}
+}
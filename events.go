@@ -0,0 +1,352 @@
+package acogo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultConvergenceWindow is the number of iterations the consensus path
+// must stay the same after events for a colony to have re-converged, unless
+// a Schedule sets another.
+const DefaultConvergenceWindow = 10
+
+// Event changes the graph a colony runs on between iterations.
+type Event interface {
+	// Apply makes the change to g, returning an error if it cannot be
+	// made. It is only called while no ants are moving through g.
+	Apply(g *Graph) error
+}
+
+// Block removes the edge From -> To from the graph with Graph.BlockEdge.
+type Block struct {
+	From, To int
+}
+
+// Apply blocks the edge.
+func (b Block) Apply(g *Graph) error {
+	return g.BlockEdge(b.From, b.To)
+}
+
+func (b Block) String() string {
+	return fmt.Sprintf("block %d -> %d", b.From, b.To)
+}
+
+// Unblock restores the edge From -> To with Graph.UnblockEdge.
+type Unblock struct {
+	From, To int
+}
+
+// Apply unblocks the edge.
+func (u Unblock) Apply(g *Graph) error {
+	return g.UnblockEdge(u.From, u.To)
+}
+
+func (u Unblock) String() string {
+	return fmt.Sprintf("unblock %d -> %d", u.From, u.To)
+}
+
+// SetCost sets the cost of the edge From -> To, which may be blocked, to
+// Cost.
+type SetCost struct {
+	From, To int
+	Cost     float64
+}
+
+// Apply sets the cost of the edge, returning an error if Cost is not
+// positive.
+func (s SetCost) Apply(g *Graph) error {
+	if s.Cost <= 0 {
+		return fmt.Errorf("cost must be positive, got %v", s.Cost)
+	}
+	e, _, err := g.findEdge(s.From, s.To)
+	if err != nil {
+		return err
+	}
+	e.Cost = s.Cost
+	return nil
+}
+
+func (s SetCost) String() string {
+	return fmt.Sprintf("cost %d -> %d %v", s.From, s.To, s.Cost)
+}
+
+// MoveGoal makes Goal the goal node of the graph with Graph.SetEnds.
+type MoveGoal struct {
+	Goal int
+}
+
+// Apply moves the goal, leaving the home node where it is.
+func (m MoveGoal) Apply(g *Graph) error {
+	return g.SetEnds(g.HomeIdx, m.Goal)
+}
+
+func (m MoveGoal) String() string {
+	return fmt.Sprintf("goal %d", m.Goal)
+}
+
+// ScheduledEvent is an event applied once a colony has completed Iteration
+// iterations, before the next one.
+type ScheduledEvent struct {
+	Iteration int
+	Event     Event
+}
+
+// byIteration sorts scheduled events by the iteration they are applied at.
+type byIteration []ScheduledEvent
+
+func (e byIteration) Len() int           { return len(e) }
+func (e byIteration) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byIteration) Less(i, j int) bool { return e[i].Iteration < e[j].Iteration }
+
+// Adaptation records how a colony adapted to the events applied at one
+// iteration.
+type Adaptation struct {
+	// Number of iterations completed when the events were applied
+	Iteration int
+	// Events applied
+	Events []Event
+	// Iteration which first found the consensus path the colony then kept
+	// for the schedule's Window iterations, or -1 if the colony has not
+	// re-converged
+	Converged int
+	// Consensus path the colony re-converged to
+	Path []int
+}
+
+// Iterations returns the number of iterations the colony took to re-converge
+// after the events, or -1 if it has not.
+func (a Adaptation) Iterations() int {
+	if a.Converged == -1 {
+		return -1
+	}
+	return a.Converged - a.Iteration
+}
+
+// Schedule is a list of events applied to the graph of a Colony between
+// iterations. A Schedule records how many iterations the colony takes to
+// re-converge after each iteration at which events are applied: the colony
+// has re-converged once its consensus path reaches the goal and stays the
+// same for Window iterations.
+type Schedule struct {
+	// Events ordered by the iteration they are applied at
+	Events []ScheduledEvent
+	// Number of iterations the consensus path must stay the same for the
+	// colony to have re-converged
+	Window int
+	// Adaptation to each iteration's events, in the order they were applied
+	Adaptations []Adaptation
+
+	// index of the first event in Events not yet applied
+	next int
+	// consensus path found by the last iteration, and the iteration which
+	// first found it
+	consensus []int
+	since     int
+}
+
+// NewSchedule creates a Schedule applying events, ordering them by iteration
+// and keeping events at the same iteration in order. The schedule has a
+// Window of DefaultConvergenceWindow.
+func NewSchedule(events []ScheduledEvent) *Schedule {
+	events = append([]ScheduledEvent(nil), events...)
+	sort.Stable(byIteration(events))
+	return &Schedule{Events: events, Window: DefaultConvergenceWindow}
+}
+
+// ReadEventsFile reads the event script at path. See ParseEvents for the
+// format of the script.
+func ReadEventsFile(path string, g *Graph) (*Schedule, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseEvents(buf, g)
+}
+
+// ParseEvents parses a script of events to apply to g, one to a line,
+// naming nodes by their names in g:
+//
+//	# iteration action nodes
+//	200 block 14 21
+//	200 block 21 14
+//	300 unblock 14 21
+//	300 unblock 21 14
+//	400 cost 5 6 10
+//	500 goal 30
+//
+// Each line gives the number of iterations after which the event is applied
+// and one of the actions block from to, unblock from to, cost from to cost,
+// or goal node. Blank lines and lines starting with # are ignored.
+func ParseEvents(buf []byte, g *Graph) (*Schedule, error) {
+	ids := make(map[string]int, len(g.Nodes))
+	for _, n := range g.Nodes {
+		ids[n.Name] = n.Id
+	}
+	var events []ScheduledEvent
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		ev, err := parseEvent(fields, ids, g)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewSchedule(events), nil
+}
+
+// parseEvent parses the fields of one line of an event script, looking up
+// nodes by name in ids and checking that edges it names are in g.
+func parseEvent(fields []string, ids map[string]int, g *Graph) (ScheduledEvent, error) {
+	if len(fields) < 2 {
+		return ScheduledEvent{}, errors.New("expected an iteration and an action")
+	}
+	iteration, err := strconv.Atoi(fields[0])
+	if err != nil || iteration < 0 {
+		return ScheduledEvent{}, fmt.Errorf("invalid iteration %v", fields[0])
+	}
+	action, args := fields[1], fields[2:]
+	argCount := map[string]int{"block": 2, "unblock": 2, "cost": 3, "goal": 1}
+	want, ok := argCount[action]
+	if !ok {
+		return ScheduledEvent{}, fmt.Errorf("unknown action %q", action)
+	}
+	if len(args) != want {
+		return ScheduledEvent{}, fmt.Errorf("%v takes %d arguments, got %d", action, want, len(args))
+	}
+	// every argument but the cost names a node
+	names := args
+	if action == "cost" {
+		names = args[:2]
+	}
+	var nodes []int
+	for _, name := range names {
+		id, ok := ids[name]
+		if !ok {
+			return ScheduledEvent{}, fmt.Errorf("no node named %v", name)
+		}
+		nodes = append(nodes, id)
+	}
+	if action != "goal" {
+		if _, _, err := g.findEdge(nodes[0], nodes[1]); err != nil {
+			return ScheduledEvent{}, err
+		}
+	}
+
+	ev := ScheduledEvent{Iteration: iteration}
+	switch action {
+	case "block":
+		ev.Event = Block{nodes[0], nodes[1]}
+	case "unblock":
+		ev.Event = Unblock{nodes[0], nodes[1]}
+	case "cost":
+		cost, err := strconv.ParseFloat(args[2], 64)
+		if err != nil || cost <= 0 {
+			return ScheduledEvent{}, fmt.Errorf("invalid cost %v", args[2])
+		}
+		ev.Event = SetCost{nodes[0], nodes[1], cost}
+	case "goal":
+		ev.Event = MoveGoal{nodes[0]}
+	}
+	return ev, nil
+}
+
+// ApplyEvents applies the events in c.Schedule due before the next
+// iteration, once c.Iteration iterations have been completed. Events due
+// before earlier iterations are skipped, as they are already reflected in
+// the graph of a colony resumed from a checkpoint. Best paths which no longer
+// lead from home to goal are forgotten, and the cost of the others
+// recomputed. ApplyEvents returns an error if an event cannot be applied or
+// the graph is no longer valid for the colony once they have been, as
+// described by Graph.Validate. Run calls
+// ApplyEvents before each iteration; callers of Step should do the same.
+func (c *Colony) ApplyEvents() error {
+	s := c.Schedule
+	if s == nil {
+		return nil
+	}
+	for s.next < len(s.Events) && s.Events[s.next].Iteration < c.Iteration {
+		s.next++
+	}
+	var applied []Event
+	for s.next < len(s.Events) && s.Events[s.next].Iteration == c.Iteration {
+		applied = append(applied, s.Events[s.next].Event)
+		s.next++
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	g := c.Graph
+	g.edits.Lock()
+	defer g.edits.Unlock()
+	for _, ev := range applied {
+		if err := ev.Apply(g); err != nil {
+			return fmt.Errorf("events at iteration %d: %v: %v", c.Iteration, ev, err)
+		}
+	}
+	if err := g.validate(c.Config.MaxSteps > 0); err != nil {
+		return fmt.Errorf("events at iteration %d: %v", c.Iteration, err)
+	}
+
+	c.Best.revalidate(g)
+	switch d := c.Config.Deposit.(type) {
+	case *RankDeposit:
+		d.Best.revalidate(g)
+	case *ElitistDeposit:
+		d.Best.revalidate(g)
+	}
+	if c.mmas != nil {
+		best := Best{c.mmas.BestPath, c.mmas.BestCost}
+		best.revalidate(g)
+		c.mmas.BestPath, c.mmas.BestCost = best.Path, best.Cost
+	}
+
+	s.Adaptations = append(s.Adaptations, Adaptation{Iteration: c.Iteration, Events: applied, Converged: -1})
+	s.consensus = nil
+	return nil
+}
+
+// observe tracks the consensus path of g after iteration, recording when
+// the colony re-converges after the last events applied.
+func (s *Schedule) observe(g *Graph, iteration int) {
+	path, err := g.ConsensusPath()
+	if err != nil {
+		path = nil
+	}
+	if path == nil || !samePath(path, s.consensus) {
+		s.consensus, s.since = path, iteration
+	}
+	if len(s.Adaptations) == 0 {
+		return
+	}
+	a := &s.Adaptations[len(s.Adaptations)-1]
+	if a.Converged == -1 && s.consensus != nil && iteration-s.since+1 >= s.Window {
+		a.Converged, a.Path = s.since, s.consensus
+	}
+}
+
+// samePath returns whether paths a and b visit the same nodes.
+func samePath(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,257 @@
+package acogo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// TestBlockEdge ensures that blocked edges are removed from their nodes and
+// restored in order when unblocked.
+func TestBlockEdge(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.3)
+	e := g.Nodes[4].InEdge(1)
+	e.pheromone = 3
+	for i := 0; i < 2; i++ {
+		if err := g.BlockEdge(1, 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.Nodes[4].InEdge(1) != nil {
+		t.Error("expected edge 1 -> 4 to be removed from node 4")
+	}
+	for _, out := range g.Nodes[1].OutEdges {
+		if out == e {
+			t.Error("expected edge 1 -> 4 to be removed from node 1")
+		}
+	}
+	if blocked := g.BlockedEdges(); len(blocked) != 1 || blocked[0] != e {
+		t.Error(fmt.Sprintf("expected edge 1 -> 4 to be blocked, got %v", blocked))
+	}
+	// the edge the other way is still there
+	if g.Nodes[1].InEdge(4) == nil {
+		t.Error("expected edge 4 -> 1 to be left in place")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := g.UnblockEdge(1, 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	validateNode(g.Nodes[1], []int{0, 2, 3, 4, 5}, Path, t)
+	if out := g.Nodes[1].OutEdges[3]; out != e || out.Pheromone() != 3 {
+		t.Error(fmt.Sprintf("expected edge 1 -> 4 to be restored in order with its pheromone, got %v", out))
+	}
+	if in := g.Nodes[4].InEdges[1]; in != e {
+		t.Error(fmt.Sprintf("expected edge 1 -> 4 to be restored in order, got %v", in))
+	}
+	if len(g.BlockedEdges()) != 0 {
+		t.Error("expected no blocked edges")
+	}
+
+	for _, ends := range [][2]int{{0, 8}, {0, 9}, {-1, 0}} {
+		if err := g.BlockEdge(ends[0], ends[1]); err == nil {
+			t.Error(fmt.Sprintf("expected an error blocking %v -> %v", ends[0], ends[1]))
+		}
+		if err := g.UnblockEdge(ends[0], ends[1]); err == nil {
+			t.Error(fmt.Sprintf("expected an error unblocking %v -> %v", ends[0], ends[1]))
+		}
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.3)
+	s, err := ParseEvents([]byte("# obstacle\n20 unblock 1 4\n\n10 block 1 4\n10 cost 4 5 2.5\n20 goal 6\n"), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ScheduledEvent{
+		{10, Block{1, 4}},
+		{10, SetCost{4, 5, 2.5}},
+		{20, Unblock{1, 4}},
+		{20, MoveGoal{6}},
+	}
+	if !reflect.DeepEqual(s.Events, expected) {
+		t.Error(fmt.Sprintf("expected events %v but got %v", expected, s.Events))
+	}
+	if s.Window != DefaultConvergenceWindow {
+		t.Error(fmt.Sprintf("expected window %v but got %v", DefaultConvergenceWindow, s.Window))
+	}
+
+	tests := []string{
+		"block 1 4",
+		"-1 block 1 4",
+		"10 remove 1 4",
+		"10 block 1",
+		"10 block 1 9",
+		"10 block 0 8",
+		"10 cost 1 4 0",
+		"10 cost 1 4 x",
+		"10 goal 1 2",
+	}
+	for _, test := range tests {
+		if _, err := ParseEvents([]byte(test), g); err == nil {
+			t.Error(fmt.Sprintf("%q: expected an error", test))
+		}
+	}
+}
+
+// obstacleMap has a short path along the top row from home to goal and a
+// long one around the bottom.
+const obstacleMap = `
+H...G
+.###.
+.....
+`
+
+// TestColonyEvents ensures that a colony whose short path is blocked
+// re-converges to the long path, and to the short path again once it is
+// unblocked.
+func TestColonyEvents(t *testing.T) {
+	for _, deterministic := range []bool{true, false} {
+		m, err := ParseMap([]byte(obstacleMap[1:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := m.Graph(0.1)
+		g.Evaporation = MultiplicativeEvaporation{0.1}
+		cfg := DefaultConfig()
+		cfg.Ant, cfg.Iterations, cfg.Seed, cfg.Deterministic = AntACS, 150, 3, deterministic
+		colony, err := NewColony(g, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		colony.Schedule, err = ParseEvents([]byte("50 block 2 3\n50 block 3 2\n100 unblock 2 3\n100 unblock 3 2\n"), g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blockedBest := false
+		colony.OnIteration = func(s IterationStats) {
+			if s.Iteration == 100 {
+				blockedBest = g.PathCost(colony.Best.Path) > 4
+			}
+		}
+		if _, err := colony.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		adaptations := colony.Schedule.Adaptations
+		if len(adaptations) != 2 {
+			t.Fatal(fmt.Sprintf("deterministic %v: expected 2 adaptations but got %v", deterministic, adaptations))
+		}
+		for i, cost := range []float64{8, 4} {
+			a := adaptations[i]
+			if a.Iterations() < 1 || a.Converged > a.Iteration+50-colony.Schedule.Window {
+				t.Error(fmt.Sprintf("deterministic %v: expected the colony to re-converge after iteration %v, got %+v", deterministic, a.Iteration, a))
+			} else if g.PathCost(a.Path) != cost {
+				t.Error(fmt.Sprintf("deterministic %v: expected the colony to re-converge to a path of cost %v after iteration %v, got %v", deterministic, cost, a.Iteration, a.Path))
+			}
+		}
+		if !blockedBest {
+			t.Error(fmt.Sprintf("deterministic %v: expected the best path to avoid the blocked edges", deterministic))
+		}
+		if colony.Best.Cost != 4 {
+			t.Error(fmt.Sprintf("deterministic %v: expected the best path to cost 4 once unblocked, got %v", deterministic, colony.Best))
+		}
+	}
+}
+
+// TestMoveGoal ensures that moving the goal forgets best paths to the old
+// goal and that events leaving the goal unreachable stop the colony.
+func TestMoveGoal(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.3)
+	cfg := DefaultConfig()
+	cfg.Iterations, cfg.Seed, cfg.Deterministic = 20, 1, true
+	colony, err := NewColony(g, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	colony.Schedule = NewSchedule([]ScheduledEvent{{10, MoveGoal{6}}})
+	colony.OnIteration = func(s IterationStats) {
+		if s.Iteration == 10 && colony.Best.Path[len(colony.Best.Path)-1] != 8 {
+			t.Error(fmt.Sprintf("expected a best path to 8 before the goal moved, got %v", colony.Best.Path))
+		}
+	}
+	if _, err := colony.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if path := colony.Best.Path; path[len(path)-1] != 6 || g.GoalIdx != 6 || g.Nodes[8].Type != Path {
+		t.Error(fmt.Sprintf("expected the goal to move to 6, got best path %v", path))
+	}
+
+	// cut node 8 off
+	var events []ScheduledEvent
+	for _, from := range []int{4, 5, 7} {
+		events = append(events, ScheduledEvent{5, Block{from, 8}})
+	}
+	g = NewGraph(3, 0, 8, 0.3)
+	colony, err = NewColony(g, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	colony.Schedule = NewSchedule(events)
+	if result, err := colony.Run(context.Background()); err == nil || result.Iterations != 5 {
+		t.Error(fmt.Sprintf("expected an unreachable goal to stop the colony after 5 iterations, got %v", result.Iterations))
+	}
+}
+
+// TestCheckpointBlocked ensures that blocked edges are restored by
+// checkpoints, so a colony resumed between events ends as one which ran
+// without stopping.
+func TestCheckpointBlocked(t *testing.T) {
+	script := []byte("5 block 1 2\n5 cost 4 5 3\n15 unblock 1 2\n")
+	newColony := func() *Colony {
+		cfg := DefaultConfig()
+		cfg.Iterations, cfg.Seed, cfg.Deterministic = 20, 7, true
+		colony, err := NewColony(NewGraph(4, 0, 15, 0.3), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if colony.Schedule, err = ParseEvents(script, colony.Graph); err != nil {
+			t.Fatal(err)
+		}
+		return colony
+	}
+
+	whole := newColony()
+	if _, err := whole.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	first := newColony()
+	first.Config.Iterations = 10
+	if _, err := first.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := first.WriteCheckpoint(&buf); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := ReadCheckpoint(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Blocked) != 1 || cp.Blocked[0].From != "1" || cp.Blocked[0].To != "2" {
+		t.Error(fmt.Sprintf("expected edge 1 -> 2 to be checkpointed as blocked, got %v", cp.Blocked))
+	}
+	cp.Config.Iterations = 20
+	resumed, err := cp.Colony()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Schedule, err = ParseEvents(script, resumed.Graph); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resumed.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(resumed.Best, whole.Best) {
+		t.Error(fmt.Sprintf("expected best path %v but got %v", whole.Best, resumed.Best))
+	}
+	if !reflect.DeepEqual(ToJSON(resumed.Graph), ToJSON(whole.Graph)) {
+		t.Error("expected the same pheromone on each edge after resuming")
+	}
+}
@@ -1,8 +1,13 @@
-package main
+package acogo
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 type NodeType int
@@ -24,101 +29,375 @@ type Graph struct {
 	// How much the pheromone on each edge decreases after each round of ants
 	// reaches the goal.
 	DecayFactor float64
+	// How pheromone evaporates from each edge when the graph is dissipated.
+	// NewGraph defaults to subtracting DecayFactor.
+	Evaporation Evaporation
+	// Least amount of pheromone allowed on each edge.
+	TauMin float64
+	// Greatest amount of pheromone allowed on each edge. If TauMax is 0,
+	// there is no upper bound.
+	TauMax float64
+	// Edge into the Home node that ants are placed on to start. It is not
+	// part of the graph and is created by Run.
+	StartEdge *Edge
+
+	// edges removed from the graph by BlockEdge, by their start and end
+	// nodes, kept so UnblockEdge can restore them
+	blocked map[[2]int]*Edge
+	// edits is held while the graph is changed between iterations, so that
+	// readers running alongside a colony, such as HeatMap, see consistent
+	// nodes and edges
+	edits sync.RWMutex
+
+	// done is closed by Stop to stop the go routines started by Run
+	done chan struct{}
+	// running tracks the go routines started by Run
+	running sync.WaitGroup
 }
 
 // NewGraph generates a new graph. The default graph at this time is a square of
 // dim * dim nodes with each node having an edge to adjacent nodes above, below,
-// left, right, and at all four diagonals.
+// left, right, and at all four diagonals. Diagonal edges have a cost of
+// sqrt(2). Nodes are laid out in a grid with node 0 in the top left corner.
 func NewGraph(dimension, homeNode, goalNode int, decayFactor float64) *Graph {
-	// generate edges and put them in a [][]*Edge 2d slice
-	edges := generateEdges(dimension)
-
-	// iterate through the [][]*Edge 2D slice to generate nodes
-	nodes := generateNodes(edges, dimension, homeNode, goalNode)
+	return NewTopologyGraph(Grid{dimension, dimension, true}, homeNode, goalNode, decayFactor)
+}
 
-	// return graph from list of nodes
+// newNamedGraph creates a Graph from the edges out of each node as
+// Topology.Edges returns them, naming each node from names.
+func newNamedGraph(names []string, edges [][]*Edge, homeNode, goalNode int, decayFactor float64) *Graph {
+	nodes := generateNodes(edges, homeNode, goalNode)
+	for id, n := range nodes {
+		n.Name = names[id]
+	}
 	return &Graph{
 		Nodes:       nodes,
 		HomeIdx:     homeNode,
 		GoalIdx:     goalNode,
 		DecayFactor: decayFactor,
+		Evaporation: SubtractiveEvaporation{decayFactor},
+		TauMin:      MinPheromone,
 	}
 }
 
-// Run calls Run on each node which calls Run on each edge initializing go
-// routines which pass ants from edge to edge in the graph.
-func (g *Graph) Run() {
+// NodeByName returns the node with the given name, or nil if there is none.
+func (g *Graph) NodeByName(name string) *Node {
 	for _, n := range g.Nodes {
-		go func(n Node) { n.Run() }(*n)
+		if n.Name == name {
+			return n
+		}
 	}
+	return nil
 }
 
-// generateEdges generates a slice of edges for a dim*dim graph such that each
-// edge connects to adjacent nodes above, below, left, right, and on all four
-// diagonals.
-func generateEdges(dim int) [][]*Edge {
-	edges := make([][]*Edge, dim*dim)
-	for i := 0; i < dim*dim; i++ {
-		edges[i] = make([]*Edge, dim*dim)
+// SetEnds makes home the home node and goal the goal node of g, returning an
+// error if either is not a node in g. The previous home and goal nodes become
+// path nodes.
+func (g *Graph) SetEnds(home, goal int) error {
+	for _, id := range []int{home, goal} {
+		if id < 0 || id >= len(g.Nodes) {
+			return fmt.Errorf("node %d is out of range, graph has nodes 0 to %d", id, len(g.Nodes)-1)
+		}
+	}
+	for _, id := range []int{g.HomeIdx, g.GoalIdx} {
+		if id >= 0 && id < len(g.Nodes) {
+			g.Nodes[id].Type = Path
+		}
 	}
+	g.HomeIdx, g.GoalIdx = home, goal
+	g.Nodes[home].Type = Home
+	g.Nodes[goal].Type = Goal
+	return nil
+}
 
-	for n := 0; n < dim*dim; n++ {
-		// edge to node above
-		if n >= dim {
-			edges[n][n-dim] = NewEdge(n, n-dim)
+// CopyPheromone sets the pheromone on each edge of g to the pheromone on the
+// edge between the nodes with the same names in src, within g.TauMin and
+// g.TauMax, returning the number of edges set. Edges of g with no match in
+// src keep their pheromone. CopyPheromone lets the output of an earlier run
+// warm start a new one.
+func (g *Graph) CopyPheromone(src *Graph) int {
+	ids := make(map[string]int, len(g.Nodes))
+	for _, n := range g.Nodes {
+		ids[n.Name] = n.Id
+	}
+	copied := 0
+	for _, n := range src.Nodes {
+		start, ok := ids[n.Name]
+		if !ok {
+			continue
 		}
-		// edge to node above/right
-		if n >= dim && n%dim != dim-1 {
-			edges[n][n-dim+1] = NewEdge(n, n-dim+1)
+		for _, se := range n.OutEdges {
+			end, ok := ids[src.Nodes[se.EndNodeId].Name]
+			if !ok {
+				continue
+			}
+			if e := g.Nodes[end].InEdge(start); e != nil {
+				p := se.Pheromone()
+				e.updatepheromone(func(float64) float64 { return g.bound(p) })
+				copied++
+			}
 		}
-		// edge to node right
-		if n%dim != dim-1 {
-			edges[n][n+1] = NewEdge(n, n+1)
+	}
+	return copied
+}
+
+// Validate checks that the home and goal nodes are distinct nodes in the
+// graph, that the goal can be reached from home and that it can be reached
+// from every node reachable from home, returning a descriptive error if not.
+// Ants wandering into a node from which the goal cannot be reached would
+// never arrive, so colonies only accept graphs with such nodes if their ants
+// give up after MaxSteps steps.
+func (g *Graph) Validate() error {
+	return g.validate(false)
+}
+
+// validate checks the graph as Validate does, but if allowTraps is set only
+// requires that the goal can be reached from home.
+func (g *Graph) validate(allowTraps bool) error {
+	if len(g.Nodes) == 0 {
+		return errors.New("graph has no nodes")
+	}
+	if g.HomeIdx < 0 || g.HomeIdx >= len(g.Nodes) {
+		return fmt.Errorf("home node %d is out of range, graph has nodes 0 to %d", g.HomeIdx, len(g.Nodes)-1)
+	}
+	if g.GoalIdx < 0 || g.GoalIdx >= len(g.Nodes) {
+		return fmt.Errorf("goal node %d is out of range, graph has nodes 0 to %d", g.GoalIdx, len(g.Nodes)-1)
+	}
+	if g.HomeIdx == g.GoalIdx {
+		return fmt.Errorf("home and goal are both node %v", g.Nodes[g.HomeIdx].Name)
+	}
+
+	fromHome := g.reachable(g.HomeIdx, func(n *Node) []*Edge { return n.OutEdges }, func(e *Edge) int { return e.EndNodeId })
+	if !fromHome[g.GoalIdx] {
+		return fmt.Errorf("goal node %v is not reachable from home node %v", g.Nodes[g.GoalIdx].Name, g.Nodes[g.HomeIdx].Name)
+	}
+	if allowTraps {
+		return nil
+	}
+	toGoal := g.reachable(g.GoalIdx, func(n *Node) []*Edge { return n.InEdges }, func(e *Edge) int { return e.StartNodeId })
+	var traps []string
+	for id, seen := range fromHome {
+		if seen && !toGoal[id] {
+			traps = append(traps, g.Nodes[id].Name)
 		}
-		// edge to node below/right
-		if dim*dim-n > dim && n%dim != dim-1 {
-			edges[n][n+dim+1] = NewEdge(n, n+dim+1)
+	}
+	if len(traps) > maxTrapsReported {
+		traps = append(traps[:maxTrapsReported], fmt.Sprintf("and %d more", len(traps)-maxTrapsReported))
+	}
+	if len(traps) > 0 {
+		return fmt.Errorf("goal node %v is not reachable from nodes %v, which are reachable from home node %v", g.Nodes[g.GoalIdx].Name, strings.Join(traps, ", "), g.Nodes[g.HomeIdx].Name)
+	}
+	return nil
+}
+
+// maxTrapsReported is the greatest number of nodes from which the goal
+// cannot be reached that Validate names.
+const maxTrapsReported = 10
+
+// reachable returns which nodes are reached by a breadth first search from
+// node start, following the edges returned by edges to the nodes returned by
+// next.
+func (g *Graph) reachable(start int, edges func(*Node) []*Edge, next func(*Edge) int) []bool {
+	seen := make([]bool, len(g.Nodes))
+	seen[start] = true
+	queue := []int{start}
+	for len(queue) > 0 {
+		n := g.Nodes[queue[0]]
+		queue = queue[1:]
+		for _, e := range edges(n) {
+			if id := next(e); !seen[id] {
+				seen[id] = true
+				queue = append(queue, id)
+			}
+		}
+	}
+	return seen
+}
+
+// Run calls Run on each node which calls Run on each edge initializing go
+// routines which pass ants from edge to edge in the graph. Ants are added to
+// the graph by sending them on StartEdge. The go routines run until Stop is
+// called.
+func (g *Graph) Run() {
+	g.done = make(chan struct{})
+	// every edge needs its channel before any ant can be sent down it
+	for _, n := range g.Nodes {
+		for _, e := range n.InEdges {
+			if e.Path == nil {
+				e.Path = make(chan Ant, edgeCapacity)
+			}
 		}
-		// edge to node below
-		if dim*dim-n > dim {
-			edges[n][n+dim] = NewEdge(n, n+dim)
+	}
+	for _, e := range g.blocked {
+		if e.Path == nil {
+			e.Path = make(chan Ant, edgeCapacity)
 		}
-		// edge to node below/left
-		if dim*dim-n > dim && n%dim != 0 {
-			edges[n][n+dim-1] = NewEdge(n, n+dim-1)
+	}
+	for _, n := range g.Nodes {
+		n.Run(g.done, &g.running)
+	}
+	// blocked edges are run too, so they can be unblocked while the graph
+	// is running
+	for _, e := range g.blocked {
+		g.running.Add(1)
+		go g.Nodes[e.EndNodeId].runAnts(e, g.done, &g.running)
+	}
+	g.StartEdge = NewEdge(g.HomeIdx, g.HomeIdx)
+	g.StartEdge.Path = make(chan Ant, edgeCapacity)
+	g.running.Add(1)
+	go g.Nodes[g.HomeIdx].runAnts(g.StartEdge, g.done, &g.running)
+}
+
+// Stop stops the go routines started by Run and waits for them to exit. Ants
+// still traveling in the graph are abandoned.
+func (g *Graph) Stop() {
+	if g.done == nil {
+		return
+	}
+	close(g.done)
+	g.running.Wait()
+	g.done = nil
+}
+
+// BlockEdge removes the edge from node from to node to from the graph, so
+// ants can no longer cross it. The edge keeps its pheromone and cost and can
+// be restored with UnblockEdge. Blocking an edge which is already blocked
+// does nothing. BlockEdge returns an error if there is no such edge. The
+// graph must not be changed while ants are moving through it.
+func (g *Graph) BlockEdge(from, to int) error {
+	e, blocked, err := g.findEdge(from, to)
+	if err != nil || blocked {
+		return err
+	}
+	start, end := g.Nodes[from], g.Nodes[to]
+	start.OutEdges = removeEdge(start.OutEdges, e)
+	end.InEdges = removeEdge(end.InEdges, e)
+	g.addBlocked(e)
+	return nil
+}
+
+// UnblockEdge restores the edge from node from to node to removed by
+// BlockEdge. Unblocking an edge which is not blocked does nothing.
+// UnblockEdge returns an error if there is no such edge. The graph must not
+// be changed while ants are moving through it.
+func (g *Graph) UnblockEdge(from, to int) error {
+	e, blocked, err := g.findEdge(from, to)
+	if err != nil || !blocked {
+		return err
+	}
+	delete(g.blocked, [2]int{from, to})
+	start, end := g.Nodes[from], g.Nodes[to]
+	start.OutEdges = insertEdge(start.OutEdges, e, func(o *Edge) bool { return o.EndNodeId > to })
+	end.InEdges = insertEdge(end.InEdges, e, func(o *Edge) bool { return o.StartNodeId > from })
+	return nil
+}
+
+// BlockedEdges returns the edges blocked by BlockEdge, ordered by the nodes
+// they come from and lead to.
+func (g *Graph) BlockedEdges() []*Edge {
+	edges := make([]*Edge, 0, len(g.blocked))
+	for _, e := range g.blocked {
+		edges = append(edges, e)
+	}
+	sort.Sort(edgesByNodes(edges))
+	return edges
+}
+
+// addBlocked adds e to the blocked edges of the graph.
+func (g *Graph) addBlocked(e *Edge) {
+	if g.blocked == nil {
+		g.blocked = make(map[[2]int]*Edge)
+	}
+	g.blocked[[2]int{e.StartNodeId, e.EndNodeId}] = e
+}
+
+// findEdge returns the edge from node from to node to and whether it is
+// blocked, or an error if there is no such edge.
+func (g *Graph) findEdge(from, to int) (*Edge, bool, error) {
+	for _, id := range []int{from, to} {
+		if id < 0 || id >= len(g.Nodes) {
+			return nil, false, fmt.Errorf("node %d is out of range, graph has nodes 0 to %d", id, len(g.Nodes)-1)
 		}
-		// edge to node left
-		if n%dim != 0 {
-			edges[n][n-1] = NewEdge(n, n-1)
+	}
+	if e := g.Nodes[to].InEdge(from); e != nil {
+		return e, false, nil
+	}
+	if e, ok := g.blocked[[2]int{from, to}]; ok {
+		return e, true, nil
+	}
+	return nil, false, fmt.Errorf("no edge from %v to %v", g.Nodes[from].Name, g.Nodes[to].Name)
+}
+
+// removeEdge removes e from edges, keeping the others in order.
+func removeEdge(edges []*Edge, e *Edge) []*Edge {
+	for i, o := range edges {
+		if o == e {
+			return append(edges[:i], edges[i+1:]...)
 		}
-		// edge to node above/left
-		if n%dim != 0 && n >= dim {
-			edges[n][n-dim-1] = NewEdge(n, n-dim-1)
+	}
+	return edges
+}
+
+// insertEdge inserts e into edges before the first edge for which after
+// returns true.
+func insertEdge(edges []*Edge, e *Edge, after func(*Edge) bool) []*Edge {
+	i := len(edges)
+	for j, o := range edges {
+		if after(o) {
+			i = j
+			break
 		}
 	}
+	edges = append(edges, nil)
+	copy(edges[i+1:], edges[i:])
+	edges[i] = e
 	return edges
 }
 
-// generateNodes generates dim*dim nodes based and gives each the in/out edges
-// mapped in the 2D slice of edges.
-func generateNodes(edges [][]*Edge, dim, homeNode, goalNode int) []*Node {
-	// in edges, each row is outgoing edges, each column is incoming edges for a given node
-	nodes := make([]*Node, dim*dim)
-	for n, row := range edges {
-		outEdges := make([]*Edge, 0, 8)
-		inEdges := make([]*Edge, 0, 8)
+// newDiagonalEdge creates a new edge between diagonally adjacent nodes in a
+// square graph with a cost of sqrt(2).
+func newDiagonalEdge(startId, endId int) *Edge {
+	e := NewEdge(startId, endId)
+	e.Cost = math.Sqrt2
+	return e
+}
 
-		// get all active edges in row for OutEdges in node
-		for _, out := range row {
-			if out != nil {
-				outEdges = append(outEdges, out)
-			}
+// generateNodes generates one node for each list of edges out of a node and
+// gives each node its in and out edges. The in and out edges of all nodes
+// are laid out in two slices, each node's edges in a section of them, so
+// generating the nodes takes time and memory in proportion to the number of
+// nodes and edges. Out edges are ordered by the node they lead to and in
+// edges by the node they come from.
+func generateNodes(edges [][]*Edge, homeNode, goalNode int) []*Node {
+	// inStart[n] is where the in edges of node n start in inEdges
+	inStart := make([]int, len(edges)+1)
+	for _, out := range edges {
+		for _, e := range out {
+			inStart[e.EndNodeId+1]++
 		}
-		// pull active edges in column for InEdges in node
-		for r := 0; r < dim*dim; r++ {
-			if edges[r][n] != nil {
-				inEdges = append(inEdges, edges[r][n])
-			}
+	}
+	for n := range edges {
+		inStart[n+1] += inStart[n]
+	}
+	outEdges := make([]*Edge, 0, inStart[len(edges)])
+	inEdges := make([]*Edge, inStart[len(edges)])
+	inNext := append([]int(nil), inStart[:len(edges)]...)
+
+	nodes := make([]*Node, len(edges))
+	for n, out := range edges {
+		start := len(outEdges)
+		outEdges = append(outEdges, out...)
+		// limit the capacity so appending to one node's edges can't
+		// overwrite the next node's
+		out = outEdges[start:len(outEdges):len(outEdges)]
+		if !edgesByEnd(out).sorted() {
+			sort.Sort(edgesByEnd(out))
+		}
+		// nodes are visited in order, so in edges are added in order of the
+		// node they come from
+		for _, e := range out {
+			inEdges[inNext[e.EndNodeId]] = e
+			inNext[e.EndNodeId]++
 		}
 		// if n == homeNode or goalNode, set NodeType as home or goal, otherwise path
 		nodeType := Path
@@ -128,44 +407,231 @@ func generateNodes(edges [][]*Edge, dim, homeNode, goalNode int) []*Node {
 		if n == goalNode {
 			nodeType = Goal
 		}
-		// create node and add to nodes
-		nodes[n] = NewNode(n, inEdges, outEdges, nodeType)
+		nodes[n] = NewNode(n, nil, out, nodeType)
+		nodes[n].Name = strconv.Itoa(n)
+	}
+	for n, node := range nodes {
+		node.InEdges = inEdges[inStart[n]:inStart[n+1]:inStart[n+1]]
 	}
 
 	return nodes
 }
 
-// Dissipate subtracts g.DecayFactor pheromone from each edge in the graph.
+// edgesByEnd sorts edges by the node they lead to.
+type edgesByEnd []*Edge
+
+func (e edgesByEnd) Len() int           { return len(e) }
+func (e edgesByEnd) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e edgesByEnd) Less(i, j int) bool { return e[i].EndNodeId < e[j].EndNodeId }
+
+// sorted returns whether the edges are already sorted, which is cheaper to
+// check than sorting them.
+func (e edgesByEnd) sorted() bool {
+	for i := 1; i < len(e); i++ {
+		if e.Less(i, i-1) {
+			return false
+		}
+	}
+	return true
+}
+
+// edgesByNodes sorts edges by the node they come from and then the node they
+// lead to.
+type edgesByNodes []*Edge
+
+func (e edgesByNodes) Len() int      { return len(e) }
+func (e edgesByNodes) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e edgesByNodes) Less(i, j int) bool {
+	if e[i].StartNodeId != e[j].StartNodeId {
+		return e[i].StartNodeId < e[j].StartNodeId
+	}
+	return e[i].EndNodeId < e[j].EndNodeId
+}
+
+// edgeList collects the edges out of each node of a graph as it is read. An
+// edge added between two nodes which are already joined replaces the edge
+// between them.
+type edgeList struct {
+	// edges out of each node, as Topology.Edges returns them
+	out [][]*Edge
+	// index of the edge from one node to another in out
+	index map[[2]int]int
+}
+
+// newEdgeList creates an edgeList for a graph of size nodes.
+func newEdgeList(size int) *edgeList {
+	return &edgeList{out: make([][]*Edge, size), index: make(map[[2]int]int)}
+}
+
+// add adds e to the list, replacing any edge between the same nodes.
+func (l *edgeList) add(e *Edge) {
+	key := [2]int{e.StartNodeId, e.EndNodeId}
+	if i, ok := l.index[key]; ok {
+		l.out[e.StartNodeId][i] = e
+		return
+	}
+	l.index[key] = len(l.out[e.StartNodeId])
+	l.out[e.StartNodeId] = append(l.out[e.StartNodeId], e)
+}
+
+// Dissipate evaporates pheromone from each edge in the graph using
+// g.Evaporation.
 func (g *Graph) Dissipate() {
+	g.Evaporate(g.Evaporation)
+}
+
+// Evaporate evaporates pheromone from each edge in the graph using ev, then
+// clears the record of which edges ants have crossed.
+func (g *Graph) Evaporate(ev Evaporation) {
 	for _, n := range g.Nodes {
 		for _, e := range n.InEdges {
-			e.Addpheromone(-1 * g.DecayFactor)
+			ev.Evaporate(g, e)
+			e.clearVisited()
 		}
 	}
 }
 
+// DissipatePath takes in a list of nodeIds representing the path an ant
+// followed and subtracts g.DecayFactor pheromone from each edge along the path.
+func (g *Graph) DissipatePath(steps []int) {
+	g.MarkPath(steps, -1*g.DecayFactor)
+}
+
 // MarkPath takes in a list of nodeIds representing the path an ant followed
 // and adds depositAmt pheromone to each edge along the path.
 func (g *Graph) MarkPath(steps []int, depositAmt float64) {
-	lastStep := steps[0]
-	for _, nodeId := range steps[0:] {
-		node := g.Nodes[nodeId]
-		node.MarkEdge(lastStep, depositAmt)
-		lastStep = nodeId
+	for i := 1; i < len(steps); i++ {
+		if e := g.Nodes[steps[i]].InEdge(steps[i-1]); e != nil {
+			g.addpheromone(e, depositAmt)
+		}
 	}
 }
 
+// ReinforcePath takes in a list of nodeIds representing a path and sets the
+// pheromone on each edge along the path to (1-rho)*pheromone + rho*amount.
+func (g *Graph) ReinforcePath(steps []int, rho, amount float64) {
+	for i := 1; i < len(steps); i++ {
+		if e := g.Nodes[steps[i]].InEdge(steps[i-1]); e != nil {
+			e.updatepheromone(func(p float64) float64 { return g.bound((1-rho)*p + rho*amount) })
+		}
+	}
+}
+
+// SetBounds sets g.TauMin and g.TauMax and moves the pheromone on each edge
+// in the graph within them.
+func (g *Graph) SetBounds(tauMin, tauMax float64) {
+	g.TauMin, g.TauMax = tauMin, tauMax
+	for _, n := range g.Nodes {
+		for _, e := range n.InEdges {
+			e.updatepheromone(g.bound)
+		}
+	}
+}
+
+// Reset sets the pheromone on each edge in the graph to p, within g.TauMin
+// and g.TauMax.
+func (g *Graph) Reset(p float64) {
+	for _, n := range g.Nodes {
+		for _, e := range n.InEdges {
+			e.updatepheromone(func(float64) float64 { return g.bound(p) })
+		}
+	}
+}
+
+// addpheromone adds f pheromone to the edge, keeping it within g.TauMin and
+// g.TauMax.
+func (g *Graph) addpheromone(e *Edge, f float64) {
+	e.updatepheromone(func(p float64) float64 { return g.bound(p + f) })
+}
+
+// bound returns p limited to g.TauMin and g.TauMax.
+func (g *Graph) bound(p float64) float64 {
+	p = math.Max(p, g.TauMin)
+	if g.TauMax > 0 {
+		p = math.Min(p, g.TauMax)
+	}
+	return p
+}
+
+// TotalPheromone returns the total pheromone on all edges in the graph.
+func (g *Graph) TotalPheromone() float64 {
+	total := 0.0
+	for _, n := range g.Nodes {
+		for _, e := range n.InEdges {
+			total += e.Pheromone()
+		}
+	}
+	return total
+}
+
+// PathCost takes in a list of nodeIds representing the path an ant followed
+// and returns the total cost of the edges along the path.
+func (g *Graph) PathCost(steps []int) float64 {
+	cost := 0.0
+	for i := 1; i < len(steps); i++ {
+		if e := g.Nodes[steps[i]].InEdge(steps[i-1]); e != nil {
+			cost += e.Cost
+		}
+	}
+	return cost
+}
+
+// ConsensusPath follows the outgoing edge with the most pheromone from the
+// home node until it reaches the goal, returning the nodeIds along the way.
+// If the path reaches a node it has already visited or a node with no
+// outgoing edges, ConsensusPath returns the path so far and an error.
+func (g *Graph) ConsensusPath() ([]int, error) {
+	visited := make([]bool, len(g.Nodes))
+	path := []int{g.HomeIdx}
+	for n := g.Nodes[g.HomeIdx]; n.Id != g.GoalIdx; {
+		visited[n.Id] = true
+
+		var next *Edge
+		for _, e := range n.OutEdges {
+			if next == nil || e.Pheromone() > next.Pheromone() {
+				next = e
+			}
+		}
+		if next == nil {
+			return path, fmt.Errorf("consensus path ends at node %v with no outgoing edges", n.Name)
+		}
+		n = g.Nodes[next.EndNodeId]
+		if visited[n.Id] {
+			return path, fmt.Errorf("consensus path loops back to node %v", n.Name)
+		}
+		path = append(path, n.Id)
+	}
+	return path, nil
+}
+
+// PathString formats a list of nodeIds as the names of the nodes separated
+// by arrows, e.g. "0 -> 7 -> 14".
+func (g *Graph) PathString(steps []int) string {
+	names := make([]string, len(steps))
+	for i, id := range steps {
+		names[i] = g.Nodes[id].Name
+	}
+	return strings.Join(names, " -> ")
+}
+
 // Node struct represents a node in the graph. It contains slices of incoming
 // and outgoing edges.
 type Node struct {
 	// Id is the numeric identity of the node.
 	Id int
+	// Name is the name of the node in DOT output. Nodes read from a DOT file
+	// keep their original names.
+	Name string
 	// InEdges are edges coming into the node.
 	InEdges []*Edge
 	// OutEdges are edges going out of the node.
 	OutEdges []*Edge
 	// Type is the type of node, one of Goal, Path, or Home.
 	Type NodeType
+	// X and Y are the position of the node in points, used to lay out DOT
+	// output. They are only meaningful if HasPos is set.
+	X, Y   float64
+	HasPos bool
 }
 
 func NewNode(id int, inEdges []*Edge, outEdges []*Edge, t NodeType) *Node {
@@ -179,74 +645,173 @@ func NewNode(id int, inEdges []*Edge, outEdges []*Edge, t NodeType) *Node {
 
 // Run starts up a go routine for each incoming edge in the node which will
 // pull ants off of the edge's channel and push them onto their next chosen
-// node.
-func (n *Node) Run() {
+// node. The go routines are added to running and exit once done is closed.
+func (n *Node) Run(done <-chan struct{}, running *sync.WaitGroup) {
 	for _, e := range n.InEdges {
-		edge := e
-		go n.runAnts(edge)
+		running.Add(1)
+		go n.runAnts(e, done, running)
 	}
 }
 
 // runAnts pulls ants from the incoming channel and then pushes them off on
-// their chosen path. If the ant is at the goal, it wil not be pushed to
-// the next channel.
-func (n *Node) runAnts(e *Edge) {
+// their chosen path using Move. If the ant is done moving, it wil not be
+// pushed to the next channel. If the next channel is full, the ant is handed
+// over from a new go routine so that runAnts never waits on another edge:
+// ants circling a cycle of full edges would otherwise wait on each other
+// forever, never taking the steps which let them give up. runAnts returns
+// once done is closed.
+func (n *Node) runAnts(e *Edge, done <-chan struct{}, running *sync.WaitGroup) {
+	defer running.Done()
 	for {
-		ant := <-e.Path
-		next, atGoal := ant.ChooseNext(n)
-		if atGoal { //ant has reached goal - no more to do
+		var ant Ant
+		select {
+		case ant = <-e.Path:
+		case <-done:
+			return
+		}
+		next, atGoal := n.Move(ant)
+		if atGoal { //ant is done moving - no more to do
 			continue
 		}
-		next.Path <- ant
+		select {
+		case next.Path <- ant:
+		default:
+			running.Add(1)
+			go handOff(ant, next, done, running)
+		}
+	}
+}
+
+// handOff sends ant down edge e, giving up once done is closed.
+func handOff(ant Ant, e *Edge, done <-chan struct{}, running *sync.WaitGroup) {
+	defer running.Done()
+	select {
+	case e.Path <- ant:
+	case <-done:
 	}
 }
 
-// MarkEdge adds depositAmt pheromone to the correct incoming edge in the
-// node.
-func (n *Node) MarkEdge(from int, depositAmt float64) {
+// Move has the ant at the node choose the next edge to move down, returning
+// the edge and whether the ant is at the goal. Ants that are LocalUpdaters
+// update the chosen edge before crossing it.
+func (n *Node) Move(ant Ant) (*Edge, bool) {
+	next, atGoal := ant.ChooseNext(n)
+	if atGoal {
+		return nil, true
+	}
+	if u, ok := ant.(LocalUpdater); ok {
+		u.LocalUpdate(next)
+	}
+	next.visit()
+	return next, false
+}
+
+// isWall returns whether the node has no edges, as walls of a GridMap don't.
+func (n *Node) isWall() bool {
+	return len(n.InEdges) == 0 && len(n.OutEdges) == 0
+}
+
+// InEdge returns the incoming edge in the node from the node with Id from,
+// or nil if there is none.
+func (n *Node) InEdge(from int) *Edge {
 	for _, e := range n.InEdges {
 		if e.StartNodeId == from {
-			e.Addpheromone(depositAmt)
+			return e
 		}
 	}
+	return nil
 }
 
 // Edge represents a directional edge in the graph.
 type Edge struct {
 	// Path is the channel on which the edge moves ants from the startNode
-	// to the endNode
+	// to the endNode. It is created by Graph.Run, so graphs which are never
+	// run don't allocate a channel for each edge.
 	Path chan Ant
 	// StartNodeId is Id of the starting node in the edge
 	StartNodeId int
 	// EndNodeId is the Id of the ending node in the edge
 	EndNodeId int
+	// Cost is the length of the edge. Ants that use heuristic information
+	// prefer edges with a lower cost.
+	Cost float64
 
-	// pheromone is the amount of pheromone currently on the edge
+	// pheromone is the amount of pheromone currently on the edge. It is
+	// guarded by mu as ants may update it while moving through the graph.
 	pheromone float64
+	// visited is whether an ant has crossed the edge since pheromone last
+	// evaporated. It is guarded by mu.
+	visited bool
+	mu      sync.Mutex
 }
 
-// NewEdge creates a new edge with the starting pheromone amount of 10.0.
+const (
+	// InitialPheromone is the amount of pheromone on a new edge.
+	InitialPheromone = 10.0
+	// MinPheromone is the least amount of pheromone an edge may have.
+	MinPheromone = 0.1
+	// GridSpacing is the distance in points between adjacent nodes in the
+	// square graph.
+	GridSpacing = 72.0
+	// edgeCapacity is the number of ants the channel of an edge can hold
+	// before ants moving onto it wait.
+	edgeCapacity = 5
+)
+
+// NewEdge creates a new edge with the starting pheromone amount of
+// InitialPheromone and a cost of 1.0.
 func NewEdge(startId, endId int) *Edge {
 	return &Edge{
-		Path:        make(chan Ant, 5),
 		StartNodeId: startId,
 		EndNodeId:   endId,
-		pheromone:   10.0,
+		Cost:        1.0,
+		pheromone:   InitialPheromone,
 	}
 }
 
 // pheromone returns the amount of pheromone present on the edge.
 func (e *Edge) Pheromone() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	return e.pheromone
 }
 
 // Addpheromone adds pheromone to the edge. Addpheromone will not allow the
-// amount of pheromone on the edge to go below 0.1.
+// amount of pheromone on the edge to go below MinPheromone.
 func (e *Edge) Addpheromone(f float64) {
-	e.pheromone = math.Max(e.pheromone+f, 0.1)
+	e.updatepheromone(func(p float64) float64 { return math.Max(p+f, MinPheromone) })
+}
+
+// updatepheromone replaces the pheromone on the edge with update(pheromone).
+func (e *Edge) updatepheromone(update func(float64) float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pheromone = update(e.pheromone)
+}
+
+// Visited returns whether an ant has crossed the edge since pheromone last
+// evaporated from the graph.
+func (e *Edge) Visited() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.visited
+}
+
+// visit records that an ant has crossed the edge.
+func (e *Edge) visit() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.visited = true
+}
+
+// clearVisited clears the record of an ant crossing the edge.
+func (e *Edge) clearVisited() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.visited = false
 }
 
 // String prints edges as "StartNodeId -> EndNodeId: pheromone".
-func (e Edge) String() string {
-	return fmt.Sprintf("%d -> %d: %.2f", e.StartNodeId, e.EndNodeId, e.pheromone)
+func (e *Edge) String() string {
+	return fmt.Sprintf("%d -> %d: %.2f", e.StartNodeId, e.EndNodeId, e.Pheromone())
 }
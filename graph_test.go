@@ -1,7 +1,8 @@
-package main
+package acogo
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"sort"
 	"testing"
@@ -54,3 +55,303 @@ func validateNode(n *Node, edgesTo []int, nodeType NodeType, t *testing.T) {
 		t.Error(fmt.Sprintf("expected node %v to have OutEdges %v but got %v", n.Id, inEdgeList, edgesTo))
 	}
 }
+
+// TestGraphCosts ensures that diagonal edges in the square graph have a cost
+// of sqrt(2) and all other edges a cost of 1.
+func TestGraphCosts(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+
+	for _, e := range g.Nodes[4].OutEdges {
+		expected := 1.0
+		if e.EndNodeId%2 == 0 {
+			expected = math.Sqrt2
+		}
+		if e.Cost != expected {
+			t.Error(fmt.Sprintf("expected edge %v to have cost %v but was %v", e, expected, e.Cost))
+		}
+	}
+}
+
+// TestPathCost ensures that PathCost totals the cost of each edge along a
+// path.
+func TestPathCost(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+
+	cost := g.PathCost([]int{0, 1, 5, 8})
+	expected := 2 + math.Sqrt2
+
+	if cost != expected {
+		t.Error(fmt.Sprintf("expected path cost %v but was %v", expected, cost))
+	}
+}
+
+// TestBounds ensures that Dissipate, Evaporate and MarkPath keep pheromone
+// within TauMin and TauMax.
+func TestBounds(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	g.SetBounds(2.0, 8.0)
+	validateBounds(g, 2.0, 8.0, t)
+
+	for i := 0; i < 20; i++ {
+		g.MarkPath([]int{0, 4, 8}, 1.0)
+	}
+	validateBounds(g, 2.0, 8.0, t)
+	if p := g.Nodes[8].InEdge(4).Pheromone(); p != 8.0 {
+		t.Error(fmt.Sprintf("expected pheromone on 4 -> 8 to be 8.0 but was %v", p))
+	}
+
+	for i := 0; i < 20; i++ {
+		g.Dissipate()
+		g.Evaporate(MultiplicativeEvaporation{0.5})
+	}
+	validateBounds(g, 2.0, 8.0, t)
+	if p := g.Nodes[8].InEdge(4).Pheromone(); p != 2.0 {
+		t.Error(fmt.Sprintf("expected pheromone on 4 -> 8 to be 2.0 but was %v", p))
+	}
+
+	g.Reset(100.0)
+	validateBounds(g, 8.0, 8.0, t)
+}
+
+// TestReinforcePath ensures that ReinforcePath moves pheromone on each edge
+// of the path towards the amount given, within TauMin and TauMax.
+func TestReinforcePath(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	g.ReinforcePath([]int{0, 4, 8}, 0.5, 2.0)
+	for _, e := range []*Edge{g.Nodes[4].InEdge(0), g.Nodes[8].InEdge(4)} {
+		if p := e.Pheromone(); p != 6.0 {
+			t.Error(fmt.Sprintf("expected pheromone on %v -> %v to be 6.0 but was %v", e.StartNodeId, e.EndNodeId, p))
+		}
+	}
+	if p := g.Nodes[1].InEdge(0).Pheromone(); p != InitialPheromone {
+		t.Error(fmt.Sprintf("expected pheromone on 0 -> 1 to be left at %v but was %v", InitialPheromone, p))
+	}
+
+	g.SetBounds(5.0, 0)
+	for i := 0; i < 20; i++ {
+		g.ReinforcePath([]int{0, 4, 8}, 0.5, 2.0)
+	}
+	validateBounds(g, 5.0, InitialPheromone, t)
+}
+
+func validateBounds(g *Graph, tauMin, tauMax float64, t *testing.T) {
+	for _, n := range g.Nodes {
+		for _, e := range n.InEdges {
+			if p := e.Pheromone(); p < tauMin || p > tauMax {
+				t.Error(fmt.Sprintf("expected edge %v to have pheromone between %v and %v", e, tauMin, tauMax))
+			}
+		}
+	}
+}
+
+// TestEvaporation ensures that each Evaporation removes the expected amount
+// of pheromone from edges.
+func TestEvaporation(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	visited := g.Nodes[4].InEdge(0)
+	other := g.Nodes[1].InEdge(0)
+
+	g.Dissipate()
+	validatePheromone(visited, 9.5, t)
+
+	g.Evaporate(MultiplicativeEvaporation{0.5})
+	validatePheromone(visited, 4.75, t)
+
+	visited.visit()
+	g.Evaporate(VisitedEvaporation{MultiplicativeEvaporation{0.5}})
+	validatePheromone(visited, 2.375, t)
+	validatePheromone(other, 4.75, t)
+	if visited.Visited() {
+		t.Error("expected visited edges to be cleared after evaporation")
+	}
+}
+
+// TestNewEvaporationErrors ensures that unknown evaporations and decays out
+// of range for an evaporation are rejected.
+func TestNewEvaporationErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		decay float64
+	}{
+		{"none", 0.3},
+		{"subtractive", -0.1},
+		{"multiplicative", 0},
+		{"multiplicative", 1.5},
+		{"visited", -0.3},
+		{"visited", 2},
+	}
+	for _, test := range tests {
+		if _, err := NewEvaporation(test.name, test.decay); err == nil {
+			t.Error(fmt.Sprintf("expected error for %v evaporation with decay %v", test.name, test.decay))
+		}
+	}
+	for _, name := range []string{"subtractive", "multiplicative", "visited"} {
+		if _, err := NewEvaporation(name, 1); err != nil {
+			t.Error(fmt.Sprintf("expected %v evaporation with decay 1, got %v", name, err))
+		}
+	}
+}
+
+func validatePheromone(e *Edge, expected float64, t *testing.T) {
+	if p := e.Pheromone(); p != expected {
+		t.Error(fmt.Sprintf("expected pheromone on %v to be %v but was %v", e, expected, p))
+	}
+}
+
+// TestValidate ensures that graphs with out of range, equal or unreachable
+// home and goal nodes are rejected, as are graphs with nodes reachable from
+// home from which the goal cannot be reached.
+func TestValidate(t *testing.T) {
+	if err := NewGraph(3, 0, 8, 0.5).Validate(); err != nil {
+		t.Error(fmt.Sprintf("expected valid graph but got %v", err))
+	}
+	for _, g := range []*Graph{
+		NewGraph(3, 9, 8, 0.5),
+		NewGraph(3, 0, -1, 0.5),
+		NewGraph(0, 0, 0, 0.5),
+		NewGraph(3, 4, 4, 0.5),
+	} {
+		if err := g.Validate(); err == nil {
+			t.Error(fmt.Sprintf("expected error validating graph with home %v and goal %v", g.HomeIdx, g.GoalIdx))
+		}
+	}
+
+	g, err := ReadDot([]byte(`digraph { a [role=home]; b [role=goal]; b -> a }`), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Validate(); err == nil {
+		t.Error("expected error validating graph with unreachable goal")
+	}
+
+	tests := []struct {
+		name string
+		dot  string
+	}{
+		{"sink", `digraph { a [role=home]; c [role=goal]; a -> b; a -> c }`},
+		{"trap", `digraph { h [role=home]; g [role=goal]; h -> g; h -> a; a -> b; b -> a }`},
+	}
+	for _, test := range tests {
+		g, err := ReadDot([]byte(test.dot), 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Validate(); err == nil {
+			t.Error(fmt.Sprintf("%v: expected error validating graph", test.name))
+		}
+		if err := g.validate(true); err != nil {
+			t.Error(fmt.Sprintf("%v: expected graph to be valid allowing traps but got %v", test.name, err))
+		}
+	}
+}
+
+// TestConsensusPath ensures that ConsensusPath follows the edges with the
+// most pheromone and stops at loops.
+func TestConsensusPath(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	g.MarkPath([]int{0, 1, 5, 8}, 1.0)
+
+	path, err := g.ConsensusPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(path, []int{0, 1, 5, 8}) {
+		t.Error(fmt.Sprintf("expected consensus path [0 1 5 8] but got %v", path))
+	}
+	if s := g.PathString(path); s != "0 -> 1 -> 5 -> 8" {
+		t.Error(fmt.Sprintf("expected path string 0 -> 1 -> 5 -> 8 but got %v", s))
+	}
+
+	g.MarkPath([]int{1, 0, 1}, 5.0)
+	path, err = g.ConsensusPath()
+	if err == nil {
+		t.Error("expected error for looping consensus path")
+	}
+	if !reflect.DeepEqual(path, []int{0, 1}) {
+		t.Error(fmt.Sprintf("expected consensus path [0 1] before loop but got %v", path))
+	}
+}
+
+// TestGridPositions ensures that the square graph is laid out in a grid
+// with node 0 in the top left corner.
+func TestGridPositions(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	for _, test := range []struct {
+		node int
+		x, y float64
+	}{
+		{0, 0, 2 * GridSpacing},
+		{5, 2 * GridSpacing, GridSpacing},
+		{7, GridSpacing, 0},
+	} {
+		n := g.Nodes[test.node]
+		if !n.HasPos || n.X != test.x || n.Y != test.y {
+			t.Error(fmt.Sprintf("expected node %v at %v,%v but got %v,%v", test.node, test.x, test.y, n.X, n.Y))
+		}
+	}
+}
+
+// TestSetEnds ensures that SetEnds moves the home and goal nodes.
+func TestSetEnds(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	if err := g.SetEnds(2, 6); err != nil {
+		t.Fatal(err)
+	}
+	if g.HomeIdx != 2 || g.GoalIdx != 6 || g.Nodes[2].Type != Home || g.Nodes[6].Type != Goal {
+		t.Error("expected home 2 and goal 6")
+	}
+	if g.Nodes[0].Type != Path || g.Nodes[8].Type != Path {
+		t.Error("expected old home and goal to become path nodes")
+	}
+	if err := g.SetEnds(0, 9); err == nil {
+		t.Error("expected error for goal out of range")
+	}
+	if n := g.NodeByName("4"); n == nil || n.Id != 4 {
+		t.Error("expected to find node 4 by name")
+	}
+}
+
+// TestCopyPheromone ensures that CopyPheromone copies pheromone between
+// edges whose nodes have the same names and leaves other edges alone.
+func TestCopyPheromone(t *testing.T) {
+	src, err := ReadDot([]byte(`digraph {
+		0 [role=home]
+		8 [role=goal]
+		0 -> 4 [pheromone=42]
+		4 -> 8 [pheromone=0.01]
+		4 -> x [pheromone=7]
+	}`), 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGraph(3, 0, 8, 0.3)
+	if n := g.CopyPheromone(src); n != 2 {
+		t.Error(fmt.Sprintf("expected 2 edges copied but got %v", n))
+	}
+	if p := g.Nodes[4].InEdge(0).Pheromone(); p != 42 {
+		t.Error(fmt.Sprintf("expected pheromone 42 on 0 -> 4 but got %v", p))
+	}
+	if p := g.Nodes[8].InEdge(4).Pheromone(); p != MinPheromone {
+		t.Error(fmt.Sprintf("expected pheromone on 4 -> 8 to be bounded to %v but got %v", MinPheromone, p))
+	}
+	if p := g.Nodes[0].InEdge(4).Pheromone(); p != InitialPheromone {
+		t.Error(fmt.Sprintf("expected pheromone on 4 -> 0 to be unchanged but got %v", p))
+	}
+}
+
+// TestEdgeList ensures that an edge added between two nodes which are
+// already joined replaces the edge between them.
+func TestEdgeList(t *testing.T) {
+	edges := newEdgeList(3)
+	first, second := NewEdge(0, 1), NewEdge(0, 1)
+	edges.add(first)
+	edges.add(NewEdge(0, 2))
+	edges.add(NewEdge(1, 0))
+	edges.add(second)
+	if len(edges.out[0]) != 2 || edges.out[0][0] != second {
+		t.Error(fmt.Sprintf("expected the second edge from 0 to 1 to replace the first, got %v", edges.out[0]))
+	}
+	if len(edges.out[1]) != 1 || len(edges.out[2]) != 0 {
+		t.Error(fmt.Sprintf("expected 1 edge out of node 1 and none out of node 2, got %v and %v", edges.out[1], edges.out[2]))
+	}
+}
@@ -0,0 +1,230 @@
+package acogo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// JSONGraph is a graph in the JSON form read by ReadJSON, e.g.
+//
+//	{
+//		"nodes": [{"name": "a", "role": "home"}, {"name": "d", "role": "goal"}],
+//		"edges": [{"from": "a", "to": "b"}, {"from": "b", "to": "d", "cost": 2.5}]
+//	}
+type JSONGraph struct {
+	// Nodes of the graph. Nodes named by edges but not listed here are added
+	// after them.
+	Nodes []JSONNode `json:"nodes"`
+	Edges []JSONEdge `json:"edges"`
+	// Whether edges are added in both directions
+	Undirected bool `json:"undirected"`
+}
+
+// JSONNode is a node of a JSONGraph.
+type JSONNode struct {
+	Name string `json:"name"`
+	// home or goal, or empty for path nodes
+	Role string `json:"role,omitempty"`
+	// Position of the node, if it has one
+	X *float64 `json:"x,omitempty"`
+	Y *float64 `json:"y,omitempty"`
+}
+
+// JSONEdge is an edge of a JSONGraph.
+type JSONEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Cost of the edge, 1.0 if not given
+	Cost float64 `json:"cost,omitempty"`
+	// Initial pheromone on the edge, InitialPheromone if not given
+	Pheromone float64 `json:"pheromone,omitempty"`
+
+	// whether cost and pheromone were given when e was read
+	hasCost, hasPheromone bool
+}
+
+// UnmarshalJSON reads e, noting whether its cost and pheromone were given so
+// that a cost or pheromone of 0 is rejected rather than replaced by the
+// default.
+func (e *JSONEdge) UnmarshalJSON(buf []byte) error {
+	var je struct {
+		From      string   `json:"from"`
+		To        string   `json:"to"`
+		Cost      *float64 `json:"cost"`
+		Pheromone *float64 `json:"pheromone"`
+	}
+	if err := json.Unmarshal(buf, &je); err != nil {
+		return err
+	}
+	*e = JSONEdge{From: je.From, To: je.To}
+	if je.Cost != nil {
+		e.Cost, e.hasCost = *je.Cost, true
+	}
+	if je.Pheromone != nil {
+		e.Pheromone, e.hasPheromone = *je.Pheromone, true
+	}
+	return nil
+}
+
+// check returns an error naming the edge if it is given a cost or pheromone
+// which is not finite and positive.
+func (e JSONEdge) check() error {
+	positive := func(f float64) bool { return f > 0 && !math.IsInf(f, 0) }
+	if (e.hasCost || e.Cost != 0) && !positive(e.Cost) {
+		return fmt.Errorf("edge %v -> %v: invalid cost %v", e.From, e.To, e.Cost)
+	}
+	if (e.hasPheromone || e.Pheromone != 0) && !positive(e.Pheromone) {
+		return fmt.Errorf("edge %v -> %v: invalid pheromone %v", e.From, e.To, e.Pheromone)
+	}
+	return nil
+}
+
+// ReadJSON parses a JSONGraph and builds an acogo Graph from it. Nodes are
+// numbered in the order they are listed, followed by nodes only named by
+// edges in the order they first appear.
+func ReadJSON(buf []byte, decayFactor float64) (*Graph, error) {
+	g, err := readJSON(buf, decayFactor)
+	if err != nil {
+		return nil, err
+	}
+	if g.HomeIdx == -1 {
+		return nil, errors.New("json graph has no node with role home")
+	}
+	if g.GoalIdx == -1 {
+		return nil, errors.New("json graph has no node with role goal")
+	}
+	return g, nil
+}
+
+// readJSON reads a JSONGraph as ReadJSON does, except that the graph's
+// HomeIdx and GoalIdx are -1 if no node has the home or goal role.
+func readJSON(buf []byte, decayFactor float64) (*Graph, error) {
+	var jg JSONGraph
+	if err := json.Unmarshal(buf, &jg); err != nil {
+		return nil, err
+	}
+	return jg.graph(decayFactor)
+}
+
+// graph builds an acogo Graph from jg as readJSON does.
+func (jg *JSONGraph) graph(decayFactor float64) (*Graph, error) {
+	ids := make(map[string]int)
+	var names []string
+	add := func(name string) int {
+		if id, ok := ids[name]; ok {
+			return id
+		}
+		ids[name] = len(names)
+		names = append(names, name)
+		return len(names) - 1
+	}
+
+	homeNode, goalNode := -1, -1
+	for _, n := range jg.Nodes {
+		if _, ok := ids[n.Name]; ok {
+			return nil, fmt.Errorf("json graph has more than one node named %v", n.Name)
+		}
+		id := add(n.Name)
+		switch n.Role {
+		case "home":
+			if homeNode != -1 {
+				return nil, fmt.Errorf("json graph has more than one home node: %v and %v", names[homeNode], n.Name)
+			}
+			homeNode = id
+		case "goal":
+			if goalNode != -1 {
+				return nil, fmt.Errorf("json graph has more than one goal node: %v and %v", names[goalNode], n.Name)
+			}
+			goalNode = id
+		case "":
+		default:
+			return nil, fmt.Errorf("node %v has unknown role %q", n.Name, n.Role)
+		}
+		if (n.X == nil) != (n.Y == nil) {
+			return nil, fmt.Errorf("node %v must have both x and y or neither", n.Name)
+		}
+	}
+	for _, e := range jg.Edges {
+		add(e.From)
+		add(e.To)
+	}
+	if len(names) == 0 {
+		return nil, errors.New("json graph has no nodes")
+	}
+
+	edges := newEdgeList(len(names))
+	for _, je := range jg.Edges {
+		if err := je.check(); err != nil {
+			return nil, err
+		}
+		start, end := ids[je.From], ids[je.To]
+		edges.add(newJSONEdge(start, end, je))
+		if jg.Undirected {
+			edges.add(newJSONEdge(end, start, je))
+		}
+	}
+
+	g := newNamedGraph(names, edges.out, homeNode, goalNode, decayFactor)
+	for _, n := range g.Nodes {
+		for _, e := range n.InEdges {
+			e.pheromone = g.bound(e.pheromone)
+		}
+	}
+	for i, n := range jg.Nodes {
+		if n.X != nil {
+			g.Nodes[i].X, g.Nodes[i].Y, g.Nodes[i].HasPos = *n.X, *n.Y, true
+		}
+	}
+	return g, nil
+}
+
+// newJSONEdge creates the edge from start to end with the cost and
+// pheromone of je, if set.
+func newJSONEdge(start, end int, je JSONEdge) *Edge {
+	e := NewEdge(start, end)
+	if je.Cost > 0 {
+		e.Cost = je.Cost
+	}
+	if je.Pheromone > 0 {
+		e.pheromone = je.Pheromone
+	}
+	return e
+}
+
+// ToJSON returns the JSONGraph for g, with the cost and current pheromone on
+// each edge, which ReadJSON reads back as the same graph.
+func ToJSON(g *Graph) JSONGraph {
+	var jg JSONGraph
+	for _, n := range g.Nodes {
+		jn := JSONNode{Name: n.Name}
+		switch n.Type {
+		case Home:
+			jn.Role = "home"
+		case Goal:
+			jn.Role = "goal"
+		}
+		if n.HasPos {
+			x, y := n.X, n.Y
+			jn.X, jn.Y = &x, &y
+		}
+		jg.Nodes = append(jg.Nodes, jn)
+	}
+	for _, n := range g.Nodes {
+		for _, e := range n.OutEdges {
+			jg.Edges = append(jg.Edges, jsonEdge(g, e))
+		}
+	}
+	return jg
+}
+
+// jsonEdge returns e of g as a JSONEdge.
+func jsonEdge(g *Graph, e *Edge) JSONEdge {
+	return JSONEdge{
+		From:      g.Nodes[e.StartNodeId].Name,
+		To:        g.Nodes[e.EndNodeId].Name,
+		Cost:      e.Cost,
+		Pheromone: e.Pheromone(),
+	}
+}
@@ -0,0 +1,96 @@
+package acogo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestReadJSON ensures that ReadJSON builds nodes and edges from an undirected
+// JSON graph, adding nodes only named by edges and setting edge cost and
+// pheromone.
+func TestReadJSON(t *testing.T) {
+	g, err := ReadJSON([]byte(`{
+		"nodes": [{"name": "a", "role": "home", "x": 0, "y": 1}, {"name": "d", "role": "goal"}],
+		"edges": [{"from": "a", "to": "b"}, {"from": "b", "to": "d", "cost": 2.5, "pheromone": 5}],
+		"undirected": true
+	}`), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.Nodes) != 3 || g.Nodes[2].Name != "b" {
+		t.Fatal(fmt.Sprintf("expected nodes a, d, b but got %v nodes", len(g.Nodes)))
+	}
+	validateNode(g.Nodes[0], []int{2}, Home, t)
+	validateNode(g.Nodes[1], []int{2}, Goal, t)
+	validateNode(g.Nodes[2], []int{0, 1}, Path, t)
+	if a := g.Nodes[0]; !a.HasPos || a.X != 0 || a.Y != 1 || g.Nodes[1].HasPos {
+		t.Error("expected only a to have a position")
+	}
+	for _, e := range []*Edge{g.Nodes[1].InEdge(2), g.Nodes[2].InEdge(1)} {
+		if e.Cost != 2.5 || e.Pheromone() != 5 {
+			t.Error(fmt.Sprintf("expected cost 2.5 and pheromone 5 between b and d but got %v, %v", e.Cost, e))
+		}
+	}
+	if e := g.Nodes[2].InEdge(0); e.Cost != 1.0 || e.Pheromone() != InitialPheromone {
+		t.Error(fmt.Sprintf("expected default cost and pheromone on a -> b but got %v, %v", e.Cost, e))
+	}
+}
+
+// TestReadJSONErrors ensures that invalid JSON graphs are rejected.
+func TestReadJSONErrors(t *testing.T) {
+	for _, src := range []string{
+		`{"edges": [{"from": "a", "to": "b"}]}`,
+		`{"nodes": [{"name": "a", "role": "home"}, {"name": "a", "role": "goal"}]}`,
+		`{"nodes": [{"name": "a", "role": "home"}, {"name": "b", "role": "start"}]}`,
+		`{"nodes": [{"name": "a", "role": "home", "x": 1}, {"name": "b", "role": "goal"}]}`,
+		`{"nodes": [{"name": "a", "role": "home"}, {"name": "b", "role": "goal"}], "edges": [{"from": "a", "to": "b", "cost": -1}]}`,
+		`{"nodes": []}`,
+	} {
+		if _, err := ReadJSON([]byte(src), 0.5); err == nil {
+			t.Error(fmt.Sprintf("expected error reading %v", src))
+		}
+	}
+}
+
+// TestReadJSONEdgeErrors ensures that edges given a cost or pheromone which
+// is not finite and positive are rejected with an error naming the edge.
+func TestReadJSONEdgeErrors(t *testing.T) {
+	for _, edge := range []string{
+		`{"from": "a", "to": "b", "cost": 0}`,
+		`{"from": "a", "to": "b", "cost": -1}`,
+		`{"from": "a", "to": "b", "pheromone": 0}`,
+		`{"from": "a", "to": "b", "pheromone": -2}`,
+	} {
+		src := `{"nodes": [{"name": "a", "role": "home"}, {"name": "b", "role": "goal"}], "edges": [` + edge + `]}`
+		if _, err := ReadJSON([]byte(src), 0.5); err == nil || !strings.Contains(err.Error(), "edge a -> b") {
+			t.Error(fmt.Sprintf("expected error naming edge a -> b reading %v but got %v", edge, err))
+		}
+	}
+	for _, je := range []JSONEdge{
+		{From: "a", To: "b", Cost: math.Inf(1)},
+		{From: "a", To: "b", Pheromone: math.NaN()},
+	} {
+		jg := JSONGraph{Nodes: []JSONNode{{Name: "a", Role: "home"}, {Name: "b", Role: "goal"}}, Edges: []JSONEdge{je}}
+		if _, err := jg.graph(0.5); err == nil {
+			t.Error(fmt.Sprintf("expected error building graph with edge %+v", je))
+		}
+	}
+}
+
+// TestReadJSONBounds ensures that pheromone read from a JSON graph is kept
+// within the graph's bounds.
+func TestReadJSONBounds(t *testing.T) {
+	g, err := ReadJSON([]byte(`{
+		"nodes": [{"name": "a", "role": "home"}, {"name": "b", "role": "goal"}],
+		"edges": [{"from": "a", "to": "b", "pheromone": 0.01}]
+	}`), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := g.Nodes[1].InEdge(0).Pheromone(); p != g.TauMin {
+		t.Error(fmt.Sprintf("expected pheromone to be raised to %v but got %v", g.TauMin, p))
+	}
+}
@@ -0,0 +1,146 @@
+package acogo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// GridMap is a grid world read from an ASCII map, with a node for each cell
+// of the map numbered row by row from the top left as in a Grid. Each free
+// cell has edges to the free cells above, below, left and right of it, and
+// if Diagonals is set, to diagonally adjacent free cells. Walls have no
+// edges.
+type GridMap struct {
+	Width, Height int
+	// Costs of entering each cell, row by row, with 0 for walls
+	Costs []float64
+	// Cells of the home and goal nodes
+	Home, Goal int
+	Diagonals  bool
+}
+
+// ReadMapFile reads the ASCII map at path. See ParseMap for the format of
+// the map.
+func ReadMapFile(path string) (*GridMap, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMap(buf)
+}
+
+// ParseMap parses an ASCII map in which each character is a cell of the
+// grid, each line a row:
+//
+//	.  a free cell
+//	#  a wall
+//	H  the home cell
+//	G  the goal cell
+//	1-9  a free cell which costs the digit to enter
+//
+// For example:
+//
+//	H..#....
+//	.#.#.##.
+//	.#...#.G
+//
+// Lines shorter than the longest line are filled out with walls. The map
+// must have exactly one home and one goal cell. The map returned has
+// diagonal edges.
+func ParseMap(buf []byte) (*GridMap, error) {
+	lines := bytes.Split(bytes.Replace(buf, []byte("\r\n"), []byte("\n"), -1), []byte("\n"))
+	for len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	m := &GridMap{Height: len(lines), Home: -1, Goal: -1, Diagonals: true}
+	for _, line := range lines {
+		if len(line) > m.Width {
+			m.Width = len(line)
+		}
+	}
+	if m.Width == 0 {
+		return nil, errors.New("map has no cells")
+	}
+
+	m.Costs = make([]float64, m.Width*m.Height)
+	for row, line := range lines {
+		for col, ch := range line {
+			n := row*m.Width + col
+			switch {
+			case ch == '#':
+			case ch == '.':
+				m.Costs[n] = 1
+			case ch >= '1' && ch <= '9':
+				m.Costs[n] = float64(ch - '0')
+			case ch == 'H' || ch == 'G':
+				end, name := &m.Home, "home"
+				if ch == 'G' {
+					end, name = &m.Goal, "goal"
+				}
+				if *end != -1 {
+					return nil, fmt.Errorf("line %d: map has more than one %v cell", row+1, name)
+				}
+				*end, m.Costs[n] = n, 1
+			default:
+				return nil, fmt.Errorf("line %d, column %d: unknown cell %q", row+1, col+1, ch)
+			}
+		}
+	}
+	if m.Home == -1 {
+		return nil, errors.New("map has no home cell H")
+	}
+	if m.Goal == -1 {
+		return nil, errors.New("map has no goal cell G")
+	}
+	return m, nil
+}
+
+// free returns whether the cell at row, col is in the map and not a wall.
+func (m *GridMap) free(row, col int) bool {
+	return row >= 0 && row < m.Height && col >= 0 && col < m.Width && m.Costs[row*m.Width+col] > 0
+}
+
+// Edges returns the edges between adjacent free cells of the map. Each edge
+// costs the cost of the cell it enters, times sqrt(2) for diagonal edges.
+// Diagonal edges are only added where both cells they pass between are free,
+// so ants can't cut the corners of walls.
+func (m *GridMap) Edges() [][]*Edge {
+	steps := rookSteps
+	if m.Diagonals {
+		steps = kingSteps
+	}
+	edges := make([][]*Edge, m.Width*m.Height)
+	for n := range edges {
+		row, col := n/m.Width, n%m.Width
+		if !m.free(row, col) {
+			continue
+		}
+		for _, s := range steps {
+			r, c := row+s.rows, col+s.cols
+			if !m.free(r, c) || !m.free(row+s.rows, col) || !m.free(row, col+s.cols) {
+				continue
+			}
+			e := NewEdge(n, r*m.Width+c)
+			e.Cost = m.Costs[r*m.Width+c]
+			if s.rows != 0 && s.cols != 0 {
+				e.Cost *= math.Sqrt2
+			}
+			edges[n] = append(edges[n], e)
+		}
+	}
+	return edges
+}
+
+// Position places cells as Grid does.
+func (m *GridMap) Position(n int) (float64, float64) {
+	return Grid{m.Width, m.Height, m.Diagonals}.Position(n)
+}
+
+// Graph generates the graph of the map's cells, with home and goal nodes at
+// the map's home and goal cells.
+func (m *GridMap) Graph(decayFactor float64) *Graph {
+	return NewTopologyGraph(m, m.Home, m.Goal, decayFactor)
+}
@@ -0,0 +1,64 @@
+package acogo
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestParseMap ensures that free cells of a map have edges to adjacent free
+// cells, that walls have none and that diagonal edges don't cut corners.
+func TestParseMap(t *testing.T) {
+	// 0 1 2 3
+	// 4 5 6 7
+	// 8 9 10 11
+	m, err := ParseMap([]byte("H.#.\r\n.2..\n#.G\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Width != 4 || m.Height != 3 || m.Home != 0 || m.Goal != 10 {
+		t.Error(fmt.Sprintf("expected a 4x3 map with home 0 and goal 10, got %vx%v with home %v and goal %v", m.Width, m.Height, m.Home, m.Goal))
+	}
+	g := m.Graph(0.3)
+	validateNode(g.Nodes[0], []int{1, 4, 5}, Home, t)
+	// 1 can't reach 6 past the wall at 2
+	validateNode(g.Nodes[1], []int{0, 4, 5}, Path, t)
+	validateNode(g.Nodes[5], []int{0, 1, 4, 6, 9, 10}, Path, t)
+	validateNode(g.Nodes[10], []int{5, 6, 9}, Goal, t)
+	// 3 can't reach 6 or 10 can't reach 7 past the walls at 2 and 11
+	validateNode(g.Nodes[3], []int{7}, Path, t)
+	// walls, including the cell missing from the last line, have no edges
+	for _, id := range []int{2, 8, 11} {
+		validateNode(g.Nodes[id], []int{}, Path, t)
+	}
+
+	costs := map[[2]int]float64{{0, 1}: 1, {0, 5}: 2 * math.Sqrt2, {4, 5}: 2, {5, 4}: 1, {9, 10}: 1}
+	for ends, cost := range costs {
+		if e := g.Nodes[ends[1]].InEdge(ends[0]); e == nil || math.Abs(e.Cost-cost) > 1e-9 {
+			t.Error(fmt.Sprintf("expected edge %v -> %v to cost %v, got %v", ends[0], ends[1], cost, e))
+		}
+	}
+
+	m.Diagonals = false
+	g = m.Graph(0.3)
+	validateNode(g.Nodes[5], []int{1, 4, 6, 9}, Path, t)
+}
+
+func TestParseMapErrors(t *testing.T) {
+	tests := []struct {
+		name, m string
+	}{
+		{"empty", "\n\n"},
+		{"no home", "..G\n"},
+		{"no goal", "H..\n"},
+		{"two homes", "H.H\n..G\n"},
+		{"two goals", "H.G\n..G\n"},
+		{"unknown cell", "H.x\n..G\n"},
+		{"zero cost", "H0G\n"},
+	}
+	for _, test := range tests {
+		if _, err := ParseMap([]byte(test.m)); err == nil {
+			t.Error(fmt.Sprintf("%v: expected an error", test.name))
+		}
+	}
+}
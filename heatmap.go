@@ -0,0 +1,151 @@
+package acogo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+)
+
+// HeatMap draws the pheromone on a graph whose nodes lie on a grid as a grid
+// of ANSI colored cells in a terminal. Each cell is colored by the most
+// pheromone on any edge out of its node and shows an arrow pointing along
+// that edge. Nodes without edges, such as the walls of a GridMap, are drawn
+// as solid blocks.
+type HeatMap struct {
+	w    io.Writer
+	g    *Graph
+	opts DotOptions
+	// node index in each cell, -1 for empty cells
+	cells [][]int
+	// whether the screen has been cleared
+	cleared bool
+}
+
+// arrows point in each of eight directions, counterclockwise from east.
+var arrows = []string{"→", "↗", "↑", "↖", "←", "↙", "↓", "↘"}
+
+// NewHeatMap creates a HeatMap drawing g to w with the scale and color map in
+// opts. NewHeatMap returns an error if any nodes of g have no position or
+// share a position.
+func NewHeatMap(w io.Writer, g *Graph, opts DotOptions) (*HeatMap, error) {
+	// find the distinct x and y positions of the nodes
+	xs, ys := make(map[float64]int), make(map[float64]int)
+	for _, n := range g.Nodes {
+		if !n.HasPos {
+			return nil, fmt.Errorf("node %v has no position", n.Name)
+		}
+		xs[n.X], ys[n.Y] = 0, 0
+	}
+	if len(g.Nodes) == 0 {
+		return nil, errors.New("graph has no nodes")
+	}
+	index := func(m map[float64]int, reverse bool) {
+		keys := make([]float64, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Float64s(keys)
+		for i, k := range keys {
+			if reverse {
+				i = len(keys) - 1 - i
+			}
+			m[k] = i
+		}
+	}
+	// rows run from the top of the graph down
+	index(xs, false)
+	index(ys, true)
+
+	cells := make([][]int, len(ys))
+	for r := range cells {
+		cells[r] = make([]int, len(xs))
+		for c := range cells[r] {
+			cells[r][c] = -1
+		}
+	}
+	for _, n := range g.Nodes {
+		r, c := ys[n.Y], xs[n.X]
+		if cells[r][c] != -1 {
+			return nil, fmt.Errorf("nodes %v and %v have the same position", g.Nodes[cells[r][c]].Name, n.Name)
+		}
+		cells[r][c] = n.Id
+	}
+
+	return &HeatMap{w: w, g: g, opts: opts, cells: cells}, nil
+}
+
+// Draw redraws the heat map from the top left corner of the terminal,
+// followed by status.
+func (h *HeatMap) Draw(status string) error {
+	var buf bytes.Buffer
+	if !h.cleared {
+		buf.WriteString("\x1b[2J")
+		h.cleared = true
+	}
+	buf.WriteString("\x1b[H")
+
+	// events may change the graph's edges while the colony runs
+	h.g.edits.RLock()
+	defer h.g.edits.RUnlock()
+	sc := newScale(h.g, h.opts)
+	for _, row := range h.cells {
+		for _, id := range row {
+			if id == -1 {
+				buf.WriteString("   ")
+				continue
+			}
+			n := h.g.Nodes[id]
+			var best *Edge
+			for _, e := range n.OutEdges {
+				if best == nil || e.Pheromone() > best.Pheromone() {
+					best = e
+				}
+			}
+
+			bg := color.RGBA{}
+			cell := " · "
+			if best != nil {
+				bg = h.opts.ColorMap.At(sc.at(best.Pheromone()))
+				cell = " " + h.arrow(best) + " "
+			}
+			if n.isWall() {
+				cell = "███"
+			}
+			switch n.Type {
+			case Home:
+				cell = "H" + cell[1:]
+			case Goal:
+				cell = " G "
+			}
+			// blend the color over a black terminal and pick a readable
+			// foreground
+			a := float64(bg.A) / 0xFF
+			r, g, b := float64(bg.R)*a, float64(bg.G)*a, float64(bg.B)*a
+			fg := 255
+			if 0.299*r+0.587*g+0.114*b > 128 {
+				fg = 0
+			}
+			fmt.Fprintf(&buf, "\x1b[48;2;%d;%d;%dm\x1b[38;2;%d;%d;%dm%v", int(r), int(g), int(b), fg, fg, fg, cell)
+		}
+		buf.WriteString("\x1b[0m\x1b[K\n")
+	}
+	fmt.Fprintf(&buf, "%v\x1b[K\n\x1b[J", status)
+
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+// arrow returns the arrow pointing along e.
+func (h *HeatMap) arrow(e *Edge) string {
+	start, end := h.g.Nodes[e.StartNodeId], h.g.Nodes[e.EndNodeId]
+	angle := math.Atan2(end.Y-start.Y, end.X-start.X)
+	i := int(math.Floor(angle/(math.Pi/4)+0.5)) % len(arrows)
+	if i < 0 {
+		i += len(arrows)
+	}
+	return arrows[i]
+}
@@ -0,0 +1,61 @@
+package acogo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestHeatMap ensures that the heat map draws a cell for each node in grid
+// order with arrows along the edges with the most pheromone.
+func TestHeatMap(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	g.MarkPath([]int{0, 4, 5, 8}, 10.0)
+
+	var buf bytes.Buffer
+	h, err := NewHeatMap(&buf, g, DefaultDotOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Draw("status"); err != nil {
+		t.Fatal(err)
+	}
+
+	// strip the escape sequences to leave the cells
+	var text []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		var plain []rune
+		escape := false
+		for _, r := range line {
+			switch {
+			case r == '\x1b':
+				escape = true
+			case escape:
+				escape = !strings.ContainsRune("mHJK", r)
+			default:
+				plain = append(plain, r)
+			}
+		}
+		text = append(text, string(plain))
+	}
+	// edges out of other nodes all have the same pheromone, so they point
+	// along their first edge
+	if !strings.HasPrefix(text[0], "H↘ ") || text[1] != " ↑  →  ↓ " {
+		t.Error(fmt.Sprintf("unexpected heat map %q", text))
+	}
+	if !strings.HasSuffix(text[2], " G ") || text[3] != "status" {
+		t.Error(fmt.Sprintf("unexpected heat map %q", text))
+	}
+}
+
+// TestHeatMapErrors ensures that graphs without a grid layout are rejected.
+func TestHeatMapErrors(t *testing.T) {
+	g, err := ReadDot([]byte(`digraph { a [role=home]; b [role=goal]; a -> b }`), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewHeatMap(&bytes.Buffer{}, g, DefaultDotOptions()); err == nil {
+		t.Error("expected error for nodes without positions")
+	}
+}
@@ -0,0 +1,492 @@
+package acogo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// States of a Job.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobCancelled = "cancelled"
+	JobFailed    = "failed"
+)
+
+// JobRequest is the body of a request to a JobServer to run a colony. The
+// graph is given either as Graph or as a DOT language graph in Dot.
+type JobRequest struct {
+	Graph *JSONGraph `json:"graph,omitempty"`
+	Dot   string     `json:"dot,omitempty"`
+	// Names of the home and goal nodes. If empty, the nodes with the home
+	// and goal roles in the graph are used.
+	Home string `json:"home,omitempty"`
+	Goal string `json:"goal,omitempty"`
+
+	// Parameters of the colony. Parameters not given keep their values from
+	// DefaultConfig.
+	Config Config `json:"config"`
+	// How pheromone evaporates, as for NewEvaporation, and the amount or
+	// proportion that evaporates each iteration
+	Evaporation string  `json:"evaporation"`
+	Decay       float64 `json:"decay"`
+	// How ants deposit pheromone and its parameters, as for NewDeposit
+	Deposit  string  `json:"deposit"`
+	Elite    float64 `json:"elite"`
+	RankAnts int     `json:"rank_ants"`
+}
+
+// newJobRequest returns a JobRequest with the defaults used by the acogo
+// command.
+func newJobRequest() JobRequest {
+	return JobRequest{
+		Config:      DefaultConfig(),
+		Evaporation: "subtractive",
+		Decay:       0.3,
+		Deposit:     "fixed",
+		Elite:       5.0,
+		RankAnts:    6,
+	}
+}
+
+// JobStatus reports the progress of a Job. Paths are given as node names.
+type JobStatus struct {
+	ID         string `json:"id"`
+	State      string `json:"state"`
+	Iteration  int    `json:"iteration"`
+	Iterations int    `json:"iterations"`
+	// Lowest cost path found so far and its cost
+	BestPath []string `json:"best_path"`
+	BestCost float64  `json:"best_cost"`
+	// Consensus path and the pheromone on each edge, once the job has
+	// finished
+	ConsensusPath []string   `json:"consensus_path,omitempty"`
+	Pheromone     []JSONEdge `json:"pheromone,omitempty"`
+	Created       time.Time  `json:"created"`
+	// Why the job failed, if it did
+	Error string `json:"error,omitempty"`
+}
+
+// Job is a colony run in the background by a JobServer.
+type Job struct {
+	colony *Colony
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	status JobStatus
+	// when the job finished, or zero if it has not
+	finished time.Time
+}
+
+// Status returns the current status of the job.
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// JobServer is an http.Handler which runs colonies submitted over HTTP in
+// the background, a limited number at a time. It serves:
+//
+//	POST /jobs              submit a JobRequest, returning the JobStatus
+//	GET /jobs               list the status of every job
+//	GET /jobs/{id}          get the status of a job
+//	POST /jobs/{id}/cancel  cancel a queued or running job
+//	DELETE /jobs/{id}       cancel a job if it has not finished and forget it
+//
+// Jobs which are larger than the server's limits are rejected, and finished
+// jobs are forgotten once they are older than FinishedTTL or more than
+// MaxFinished jobs have finished since. The limits must not be changed once
+// the server is serving requests.
+type JobServer struct {
+	// Greatest number of ants, iterations and graph nodes a job may have
+	MaxAnts, MaxIterations, MaxNodes int
+	// How long finished jobs are kept and the greatest number kept
+	FinishedTTL time.Duration
+	MaxFinished int
+
+	// number of jobs run at once and the number which may wait for them
+	maxJobs, maxQueued int
+	// slots holds a value for each running job
+	slots chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+	// ids of jobs in the order they were submitted
+	order []string
+	// number of jobs queued or running
+	active int
+}
+
+// maxRequestSize is the largest JobRequest a JobServer reads.
+const maxRequestSize = 10 << 20
+
+// Default limits of a JobServer.
+const (
+	DefaultMaxJobAnts       = 10000
+	DefaultMaxJobIterations = 1000000
+	DefaultMaxJobNodes      = 100000
+	DefaultFinishedTTL      = time.Hour
+	DefaultMaxFinished      = 100
+)
+
+// NewJobServer creates a JobServer running at most maxJobs jobs at a time,
+// with at most maxQueued more waiting to run, and the default limits.
+func NewJobServer(maxJobs, maxQueued int) *JobServer {
+	return &JobServer{
+		MaxAnts:       DefaultMaxJobAnts,
+		MaxIterations: DefaultMaxJobIterations,
+		MaxNodes:      DefaultMaxJobNodes,
+		FinishedTTL:   DefaultFinishedTTL,
+		MaxFinished:   DefaultMaxFinished,
+		maxJobs:       maxJobs,
+		maxQueued:     maxQueued,
+		slots:         make(chan struct{}, maxJobs),
+		jobs:          make(map[string]*Job),
+	}
+}
+
+// ServeHTTP routes requests to the job API.
+func (s *JobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.evict(time.Now())
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "jobs" && r.Method == "POST":
+		s.submit(w, r)
+	case path == "jobs" && r.Method == "GET":
+		s.list(w)
+	case len(parts) == 2 && parts[0] == "jobs" && r.Method == "GET":
+		if j := s.Job(parts[1]); j != nil {
+			writeJSON(w, http.StatusOK, j.Status())
+		} else {
+			jobError(w, http.StatusNotFound, "no job %v", parts[1])
+		}
+	case len(parts) == 3 && parts[0] == "jobs" && parts[2] == "cancel" && r.Method == "POST":
+		if j := s.Job(parts[1]); j != nil {
+			j.cancel()
+			writeJSON(w, http.StatusOK, j.Status())
+		} else {
+			jobError(w, http.StatusNotFound, "no job %v", parts[1])
+		}
+	case len(parts) == 2 && parts[0] == "jobs" && r.Method == "DELETE":
+		if j := s.remove(parts[1]); j != nil {
+			j.cancel()
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			jobError(w, http.StatusNotFound, "no job %v", parts[1])
+		}
+	case path == "jobs" || (len(parts) >= 2 && parts[0] == "jobs"):
+		jobError(w, http.StatusMethodNotAllowed, "method %v not allowed", r.Method)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// submit creates a job from the JobRequest in the body of r and starts it
+// once there is a free slot.
+func (s *JobServer) submit(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		jobError(w, http.StatusBadRequest, "reading request: %v", err)
+		return
+	}
+	req := newJobRequest()
+	if err := json.Unmarshal(body, &req); err != nil {
+		jobError(w, http.StatusBadRequest, "invalid request: %v", err)
+		return
+	}
+	colony, err := req.colony(s)
+	if err != nil {
+		jobError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		jobError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &Job{colony: colony, cancel: cancel}
+	j.status = JobStatus{ID: id, State: JobQueued, Iterations: colony.Config.Iterations, Created: time.Now()}
+	colony.OnIteration = func(stats IterationStats) {
+		j.mu.Lock()
+		j.status.Iteration = stats.Iteration
+		j.status.BestPath = pathNames(colony.Graph, colony.Best.Path)
+		j.status.BestCost = colony.Best.Cost
+		j.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	if s.active >= s.maxJobs+s.maxQueued {
+		s.mu.Unlock()
+		cancel()
+		jobError(w, http.StatusServiceUnavailable, "too many jobs waiting to run")
+		return
+	}
+	s.active++
+	s.jobs[id] = j
+	s.order = append(s.order, id)
+	s.mu.Unlock()
+
+	go s.run(ctx, j)
+	writeJSON(w, http.StatusAccepted, j.Status())
+}
+
+// run waits for a free slot and then runs j until it finishes or ctx is
+// cancelled. A job which panics or returns an error other than being
+// cancelled has failed.
+func (s *JobServer) run(ctx context.Context, j *Job) {
+	defer j.cancel()
+	defer func() {
+		s.mu.Lock()
+		s.active--
+		s.mu.Unlock()
+	}()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+	}
+
+	state, err := JobCancelled, error(nil)
+	if ctx.Err() == nil {
+		j.mu.Lock()
+		j.status.State = JobRunning
+		j.mu.Unlock()
+		switch err = runColony(ctx, j.colony); {
+		case err == nil:
+			state = JobDone
+		case err != ctx.Err():
+			state = JobFailed
+		default:
+			err = nil
+		}
+	}
+
+	g := j.colony.Graph
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.State = state
+	j.finished = time.Now()
+	if err != nil {
+		j.status.Error = err.Error()
+	}
+	if path, err := g.ConsensusPath(); err == nil {
+		j.status.ConsensusPath = pathNames(g, path)
+	}
+	j.status.Pheromone = ToJSON(g).Edges
+}
+
+// runColony runs colony until it finishes or ctx is cancelled, returning
+// any panic in the colony as an error.
+func runColony(ctx context.Context, colony *Colony) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("colony panicked: %v", r)
+		}
+	}()
+	_, err = colony.Run(ctx)
+	return err
+}
+
+// colony builds the graph and colony described by the request, returning an
+// error if the graph is not valid, as described by Graph.Validate, or the
+// job is larger than the limits of s.
+func (req *JobRequest) colony(s *JobServer) (*Colony, error) {
+	var g *Graph
+	var err error
+	switch {
+	case req.Graph != nil && req.Dot != "":
+		return nil, errors.New("request must give one of graph or dot, not both")
+	case req.Graph != nil:
+		g, err = req.Graph.graph(req.Decay)
+	case req.Dot != "":
+		g, err = readDot([]byte(req.Dot), req.Decay)
+	default:
+		return nil, errors.New("request must give a graph or dot")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid graph: %v", err)
+	}
+	if len(g.Nodes) > s.MaxNodes {
+		return nil, fmt.Errorf("graph has %d nodes, more than the limit of %d", len(g.Nodes), s.MaxNodes)
+	}
+
+	home, goal := g.HomeIdx, g.GoalIdx
+	if req.Home != "" {
+		n := g.NodeByName(req.Home)
+		if n == nil {
+			return nil, fmt.Errorf("no home node named %v", req.Home)
+		}
+		home = n.Id
+	}
+	if req.Goal != "" {
+		n := g.NodeByName(req.Goal)
+		if n == nil {
+			return nil, fmt.Errorf("no goal node named %v", req.Goal)
+		}
+		goal = n.Id
+	}
+	if home == -1 || goal == -1 {
+		return nil, errors.New("request must name the home and goal nodes or mark them in the graph")
+	}
+	if err := g.SetEnds(home, goal); err != nil {
+		return nil, err
+	}
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+
+	if g.Evaporation, err = NewEvaporation(req.Evaporation, req.Decay); err != nil {
+		return nil, err
+	}
+	cfg := req.Config
+	if cfg.Deposit, err = NewDeposit(req.Deposit, cfg.DepositAmt, req.Elite, req.RankAnts); err != nil {
+		return nil, err
+	}
+	if cfg.Iterations < 1 {
+		return nil, errors.New("colony must run at least one iteration")
+	}
+	if cfg.Iterations > s.MaxIterations {
+		return nil, fmt.Errorf("%d iterations is more than the limit of %d", cfg.Iterations, s.MaxIterations)
+	}
+	if cfg.AntCount > s.MaxAnts {
+		return nil, fmt.Errorf("%d ants is more than the limit of %d", cfg.AntCount, s.MaxAnts)
+	}
+	return NewColony(g, cfg)
+}
+
+// Job returns the job with the given id, or nil if there is none.
+func (s *JobServer) Job(id string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id]
+}
+
+// remove forgets the job with the given id, returning it, or nil if there
+// is none.
+func (s *JobServer) remove(id string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := s.jobs[id]
+	if j == nil {
+		return nil
+	}
+	delete(s.jobs, id)
+	for i, other := range s.order {
+		if other == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return j
+}
+
+// evict forgets jobs which finished more than s.FinishedTTL before now, and
+// the oldest finished jobs beyond the s.MaxFinished most recent.
+func (s *JobServer) evict(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var finished []finishedJob
+	for _, id := range s.order {
+		if at := s.jobs[id].finishedAt(); !at.IsZero() {
+			finished = append(finished, finishedJob{id, at})
+		}
+	}
+	sort.Sort(byFinished(finished))
+	for i, f := range finished {
+		if i >= s.MaxFinished || now.Sub(f.at) > s.FinishedTTL {
+			delete(s.jobs, f.id)
+		}
+	}
+
+	order := s.order[:0]
+	for _, id := range s.order {
+		if s.jobs[id] != nil {
+			order = append(order, id)
+		}
+	}
+	s.order = order
+}
+
+// finishedJob is the id of a job and when it finished.
+type finishedJob struct {
+	id string
+	at time.Time
+}
+
+// byFinished sorts finished jobs from the most recently finished.
+type byFinished []finishedJob
+
+func (f byFinished) Len() int           { return len(f) }
+func (f byFinished) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+func (f byFinished) Less(i, j int) bool { return f[i].at.After(f[j].at) }
+
+// finishedAt returns when the job finished, or zero if it has not.
+func (j *Job) finishedAt() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.finished
+}
+
+// list writes the status of every job in the order they were submitted.
+func (s *JobServer) list(w http.ResponseWriter) {
+	s.mu.Lock()
+	jobs := make([]*Job, len(s.order))
+	for i, id := range s.order {
+		jobs[i] = s.jobs[id]
+	}
+	s.mu.Unlock()
+
+	statuses := make([]JobStatus, len(jobs))
+	for i, j := range jobs {
+		// leave out the pheromone on each edge to keep the list short
+		statuses[i] = j.Status()
+		statuses[i].Pheromone = nil
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// newJobID returns a random job id.
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// pathNames returns the names of the nodes along path.
+func pathNames(g *Graph, path []int) []string {
+	names := make([]string, len(path))
+	for i, id := range path {
+		names[i] = g.Nodes[id].Name
+	}
+	return names
+}
+
+// writeJSON writes v as the JSON body of a response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// jobError writes an error response with a JSON body.
+func jobError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
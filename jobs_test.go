@@ -0,0 +1,198 @@
+package acogo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postJob submits a job to the server at url, returning the response status
+// and the job's status.
+func postJob(url, body string, t *testing.T) (int, JobStatus) {
+	resp, err := http.Post(url+"/jobs", "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var status JobStatus
+	json.NewDecoder(resp.Body).Decode(&status)
+	return resp.StatusCode, status
+}
+
+// getJob gets the status of the job with the given id.
+func getJob(url, id string, t *testing.T) JobStatus {
+	resp, err := http.Get(url + "/jobs/" + id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var status JobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+// waitJob polls the job with the given id until it is in state.
+func waitJob(url, id, state string, t *testing.T) JobStatus {
+	for i := 0; i < 500; i++ {
+		if status := getJob(url, id, t); status.State == state {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal(fmt.Sprintf("job %v never reached state %v", id, state))
+	return JobStatus{}
+}
+
+// TestJobServer ensures that jobs given as JSON or DOT run to completion and
+// report their best path and the final pheromone.
+func TestJobServer(t *testing.T) {
+	ts := httptest.NewServer(NewJobServer(2, 2))
+	defer ts.Close()
+
+	for _, body := range []string{
+		`{"graph": {"edges": [{"from": "a", "to": "b"}, {"from": "b", "to": "d"}, {"from": "a", "to": "d", "cost": 5}]},
+		  "home": "a", "goal": "d", "config": {"iterations": 20, "ant": "as"}}`,
+		`{"dot": "digraph { a [role=home]; d [role=goal]; a -> b -> d; a -> d [cost=5] }", "config": {"iterations": 20}}`,
+	} {
+		code, status := postJob(ts.URL, body, t)
+		if code != http.StatusAccepted || status.ID == "" || status.Iterations != 20 {
+			t.Fatal(fmt.Sprintf("expected job to be accepted but got %v %+v", code, status))
+		}
+		status = waitJob(ts.URL, status.ID, JobDone, t)
+		if status.Iteration != 20 || fmt.Sprint(status.BestPath) != "[a b d]" || status.BestCost != 2 {
+			t.Error(fmt.Sprintf("expected best path a b d after 20 iterations but got %+v", status))
+		}
+		if len(status.Pheromone) != 3 {
+			t.Error(fmt.Sprintf("expected pheromone on 3 edges but got %v", status.Pheromone))
+		}
+	}
+}
+
+// TestJobServerLimits ensures that jobs wait for a free slot, that jobs
+// beyond the queue are refused and that jobs can be cancelled.
+func TestJobServerLimits(t *testing.T) {
+	ts := httptest.NewServer(NewJobServer(1, 1))
+	defer ts.Close()
+
+	long := `{"graph": {"nodes": [{"name": "a", "role": "home"}, {"name": "b", "role": "goal"}],
+		"edges": [{"from": "a", "to": "b"}]}, "config": {"iterations": 1000000}}`
+	_, running := postJob(ts.URL, long, t)
+	waitJob(ts.URL, running.ID, JobRunning, t)
+	_, queued := postJob(ts.URL, long, t)
+	if queued.State != JobQueued {
+		t.Error(fmt.Sprintf("expected second job to be queued but got %v", queued.State))
+	}
+	if code, _ := postJob(ts.URL, long, t); code != http.StatusServiceUnavailable {
+		t.Error(fmt.Sprintf("expected third job to be refused but got %v", code))
+	}
+
+	if resp, err := http.Post(ts.URL+"/jobs/"+running.ID+"/cancel", "", nil); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatal("expected cancel to succeed")
+	}
+	waitJob(ts.URL, running.ID, JobCancelled, t)
+	waitJob(ts.URL, queued.ID, JobRunning, t)
+
+	req, _ := http.NewRequest("DELETE", ts.URL+"/jobs/"+queued.ID, nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatal("expected delete to succeed")
+	}
+	if resp, err := http.Get(ts.URL + "/jobs/" + queued.ID); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Error("expected deleted job to be gone")
+	}
+}
+
+// TestJobServerErrors ensures that invalid jobs and jobs beyond the server's
+// limits are rejected.
+func TestJobServerErrors(t *testing.T) {
+	s := NewJobServer(1, 1)
+	s.MaxNodes = 4
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for _, body := range []string{
+		`not json`,
+		`{}`,
+		`{"dot": "digraph { a -> b }"}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "c"}`,
+		`{"dot": "digraph { a -> b }", "home": "b", "goal": "a"}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "config": {"ant": "bee"}}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "deposit": "none"}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "a"}`,
+		`{"dot": "digraph { h -> g; h -> a; a -> b; b -> a }", "home": "h", "goal": "g", "config": {"max_steps": 50}}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "config": {"iterations": 1000001}}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "config": {"ant_count": 10001}}`,
+		`{"dot": "digraph { a -> b -> c -> d -> e }", "home": "a", "goal": "e"}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "config": {"ant": "acs", "q0": 5}}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "config": {"ant": "acs", "xi": -1}}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "config": {"ant": "mmas", "pbest": 1}}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "config": {"alpha": -1}}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "evaporation": "multiplicative", "decay": -1}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "deposit": "rank", "rank_ants": 0}`,
+		`{"dot": "digraph { a -> b }", "home": "a", "goal": "b", "deposit": "elitist", "elite": -1}`,
+	} {
+		if code, _ := postJob(ts.URL, body, t); code != http.StatusBadRequest {
+			t.Error(fmt.Sprintf("expected %v to be rejected but got %v", body, code))
+		}
+	}
+}
+
+// TestJobServerPanic ensures that a job whose colony panics fails rather
+// than taking the server down.
+func TestJobServerPanic(t *testing.T) {
+	s := NewJobServer(1, 1)
+	cfg := DefaultConfig()
+	cfg.Deterministic = true
+	colony, err := NewColony(NewGraph(3, 0, 8, 0.3), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	colony.OnIteration = func(IterationStats) { panic("out of pheromone") }
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &Job{colony: colony, cancel: cancel}
+	s.active++
+	s.run(ctx, j)
+
+	if status := j.Status(); status.State != JobFailed || !strings.Contains(status.Error, "out of pheromone") {
+		t.Error(fmt.Sprintf("expected job to fail with the panic but got %+v", status))
+	}
+	if s.active != 0 || len(s.slots) != 0 {
+		t.Error("expected the job to free its slot")
+	}
+}
+
+// TestJobServerEvict ensures that finished jobs are forgotten once they are
+// too old or too many jobs have finished since.
+func TestJobServerEvict(t *testing.T) {
+	s := NewJobServer(1, 1)
+	s.MaxFinished = 2
+	now := time.Now()
+	for i, finished := range []time.Duration{0, -time.Minute, -2 * time.Minute, -2 * time.Hour} {
+		j := &Job{}
+		if finished != 0 {
+			j.finished = now.Add(finished)
+		}
+		id := fmt.Sprint(i)
+		s.jobs[id] = j
+		s.order = append(s.order, id)
+	}
+
+	// job 0 has not finished and 3 is too old
+	s.evict(now)
+	if fmt.Sprint(s.order) != "[0 1 2]" || len(s.jobs) != 3 {
+		t.Error(fmt.Sprintf("expected jobs 0 to 2 to be kept but got %v", s.order))
+	}
+	// 2 finished before 1
+	s.MaxFinished = 1
+	s.evict(now)
+	if fmt.Sprint(s.order) != "[0 1]" || len(s.jobs) != 2 {
+		t.Error(fmt.Sprintf("expected jobs 0 and 1 to be kept but got %v", s.order))
+	}
+}
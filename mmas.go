@@ -0,0 +1,116 @@
+package acogo
+
+import (
+	"math"
+)
+
+// MMAS performs the global pheromone update of the MAX-MIN Ant System. Only
+// one ant deposits pheromone each iteration, and pheromone on every edge is
+// kept between bounds derived from the cost of the best path found so far.
+type MMAS struct {
+	// Amount of pheromone deposited by the best ant, divided by the cost of
+	// its path
+	DepositAmt float64
+	// Rate at which pheromone evaporates from each edge every iteration
+	Rho float64
+	// Probability of an ant constructing the best path once the colony has
+	// converged, used to derive TauMin
+	PBest float64
+	// Whether the global-best ant deposits pheromone rather than the
+	// iteration-best ant
+	GlobalBest bool
+	// Number of iterations without a better path after which pheromone on
+	// every edge is reset to TauMax
+	StagnationLimit int
+
+	// Best path found so far and its cost
+	BestPath []int
+	BestCost float64
+
+	// number of iterations since BestPath last improved
+	stagnant int
+}
+
+// NewMMAS creates an MMAS with the input parameters.
+func NewMMAS(depositAmt, rho, pBest float64, globalBest bool, stagnationLimit int) *MMAS {
+	return &MMAS{
+		DepositAmt:      depositAmt,
+		Rho:             rho,
+		PBest:           pBest,
+		GlobalBest:      globalBest,
+		StagnationLimit: stagnationLimit,
+	}
+}
+
+// Update evaporates pheromone on g and lays down pheromone on the path of the
+// iteration-best or global-best ant. The first time Update is called, and
+// whenever the colony has not found a better path in StagnationLimit
+// iterations, pheromone on each edge is reset to TauMax.
+func (m *MMAS) Update(g *Graph, ants []Ant) {
+	var iterPath []int
+	iterCost := 0.0
+	for _, ant := range ants {
+		path := ant.Path()
+		if cost := g.PathCost(path); iterPath == nil || cost < iterCost {
+			iterPath, iterCost = path, cost
+		}
+	}
+	if iterPath == nil {
+		return
+	}
+
+	first := m.BestPath == nil
+	if first || iterCost < m.BestCost {
+		m.BestPath, m.BestCost = iterPath, iterCost
+		m.stagnant = 0
+		if tauMin, tauMax, ok := m.bounds(g); ok {
+			g.SetBounds(tauMin, tauMax)
+		}
+	} else {
+		m.stagnant++
+	}
+	if first && g.TauMax > 0 {
+		g.Reset(g.TauMax)
+	}
+
+	g.Evaporate(MultiplicativeEvaporation{m.Rho})
+	if m.GlobalBest {
+		g.MarkPath(m.BestPath, m.DepositAmt/m.BestCost)
+	} else {
+		g.MarkPath(iterPath, m.DepositAmt/iterCost)
+	}
+
+	if m.StagnationLimit > 0 && m.stagnant >= m.StagnationLimit && g.TauMax > 0 {
+		g.Reset(g.TauMax)
+		m.stagnant = 0
+	}
+}
+
+// bounds derives TauMin and TauMax from the cost of the best path found so
+// far. TauMax is DepositAmt / (Rho * BestCost), the limit pheromone on the
+// best path converges to. TauMin is chosen so that once every edge on the
+// best path has TauMax pheromone and every other edge TauMin, an ant making
+// one decision per node in the graph constructs the best path with
+// probability PBest. bounds returns false if TauMax is not finite and
+// positive, as when the best path costs nothing, in which case the bounds
+// should be left as they are.
+func (m *MMAS) bounds(g *Graph) (float64, float64, bool) {
+	tauMax := m.DepositAmt / (m.Rho * m.BestCost)
+	if math.IsInf(tauMax, 0) || math.IsNaN(tauMax) || tauMax <= 0 {
+		return 0, 0, false
+	}
+
+	// average number of edges to choose between at each node
+	edges := 0
+	for _, n := range g.Nodes {
+		edges += len(n.OutEdges)
+	}
+	avg := float64(edges) / float64(len(g.Nodes))
+
+	pDec := math.Pow(m.PBest, 1/float64(len(g.Nodes)))
+	tauMin := tauMax * (1 - pDec) / ((avg - 1) * pDec)
+	if avg <= 1 || tauMin > tauMax || math.IsNaN(tauMin) {
+		tauMin = tauMax
+	}
+	return tauMin, tauMax, true
+}
@@ -0,0 +1,91 @@
+package acogo
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// pathAnt is an Ant which has already taken a fixed path.
+type pathAnt []int
+
+func (a pathAnt) ChooseNext(*Node) (*Edge, bool) { return nil, true }
+func (a pathAnt) MarkPath(g *Graph)              { g.MarkPath(a, 1.0) }
+func (a pathAnt) Path() []int                    { return a }
+func (a pathAnt) Failed() bool                   { return false }
+
+// TestMMASUpdate ensures that only the iteration-best ant deposits pheromone
+// and that pheromone stays within bounds derived from the best path.
+func TestMMASUpdate(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	m := NewMMAS(1.0, 0.1, 0.05, false, 0)
+
+	ants := []Ant{pathAnt{0, 1, 2, 5, 8}, pathAnt{0, 4, 8}}
+	m.Update(g, ants)
+
+	cost := 2 * math.Sqrt2
+	if m.BestCost != cost {
+		t.Error(fmt.Sprintf("expected best cost %v but was %v", cost, m.BestCost))
+	}
+	tauMax := 1.0 / (0.1 * cost)
+	if g.TauMax != tauMax {
+		t.Error(fmt.Sprintf("expected TauMax %v but was %v", tauMax, g.TauMax))
+	}
+	if g.TauMin <= 0 || g.TauMin >= g.TauMax {
+		t.Error(fmt.Sprintf("expected TauMin between 0 and %v but was %v", g.TauMax, g.TauMin))
+	}
+	// pheromone starts at TauMax, so the best path stays there and all other
+	// edges evaporate
+	if p := g.Nodes[4].InEdge(0).Pheromone(); p != tauMax {
+		t.Error(fmt.Sprintf("expected pheromone on 0 -> 4 to be %v but was %v", tauMax, p))
+	}
+	if p := g.Nodes[1].InEdge(0).Pheromone(); p != 0.9*tauMax {
+		t.Error(fmt.Sprintf("expected pheromone on 0 -> 1 to be %v but was %v", 0.9*tauMax, p))
+	}
+
+	for i := 0; i < 100; i++ {
+		m.Update(g, ants)
+	}
+	validateBounds(g, g.TauMin, g.TauMax, t)
+	if p := g.Nodes[1].InEdge(0).Pheromone(); p != g.TauMin {
+		t.Error(fmt.Sprintf("expected pheromone on 0 -> 1 to be %v but was %v", g.TauMin, p))
+	}
+}
+
+// TestMMASStagnation ensures that pheromone is reset to TauMax when no better
+// path is found in StagnationLimit iterations.
+func TestMMASStagnation(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	m := NewMMAS(1.0, 0.1, 0.05, true, 5)
+
+	ants := []Ant{pathAnt{0, 4, 8}}
+	for i := 0; i < 5; i++ {
+		m.Update(g, ants)
+	}
+	if p := g.Nodes[1].InEdge(0).Pheromone(); p >= g.TauMax {
+		t.Error(fmt.Sprintf("expected pheromone on 0 -> 1 to evaporate but was %v", p))
+	}
+
+	m.Update(g, ants)
+	validateBounds(g, g.TauMax, g.TauMax, t)
+}
+
+// TestMMASZeroCost ensures that a best path which costs nothing leaves the
+// bounds unset rather than making TauMax infinite.
+func TestMMASZeroCost(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	m := NewMMAS(1.0, 0.1, 0.05, true, 1)
+	for i := 0; i < 3; i++ {
+		m.Update(g, []Ant{pathAnt{0}})
+	}
+	if g.TauMax != 0 {
+		t.Error(fmt.Sprintf("expected TauMax to be left unset but was %v", g.TauMax))
+	}
+	for _, n := range g.Nodes {
+		for _, e := range n.InEdges {
+			if p := e.Pheromone(); math.IsInf(p, 0) || math.IsNaN(p) {
+				t.Error(fmt.Sprintf("expected finite pheromone but edge %v -> %v has %v", e.StartNodeId, e.EndNodeId, p))
+			}
+		}
+	}
+}
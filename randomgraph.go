@@ -0,0 +1,251 @@
+package acogo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// RandomGraphAttempts is the number of graphs NewRandomGraph draws looking
+// for one in which the goal can be reached from home.
+const RandomGraphAttempts = 100
+
+// Generator draws random graphs. Edges of generated graphs go in both
+// directions.
+type Generator interface {
+	// Generate draws a Topology using r, returning an error if the
+	// generator's parameters are invalid.
+	Generate(r *rand.Rand) (Topology, error)
+}
+
+// NewRandomGraph draws a graph from gen using a source of randomness seeded
+// with seed, so the same seed gives the same graph. Graphs in which the goal
+// cannot be reached from home are drawn again, and NewRandomGraph returns an
+// error if none of RandomGraphAttempts graphs connect them.
+func NewRandomGraph(gen Generator, seed int64, homeNode, goalNode int, decayFactor float64) (*Graph, error) {
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < RandomGraphAttempts; i++ {
+		t, err := gen.Generate(r)
+		if err != nil {
+			return nil, err
+		}
+		size := len(t.Edges())
+		for _, id := range []int{homeNode, goalNode} {
+			if id < 0 || id >= size {
+				return nil, fmt.Errorf("node %d is out of range, graph has nodes 0 to %d", id, size-1)
+			}
+		}
+		if g := NewTopologyGraph(t, homeNode, goalNode, decayFactor); g.Validate() == nil {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("none of %d graphs drawn connect node %d to node %d", RandomGraphAttempts, homeNode, goalNode)
+}
+
+// fixedTopology is a Topology with edges and positions which have already
+// been drawn.
+type fixedTopology struct {
+	edges [][]*Edge
+	x, y  []float64
+}
+
+func (t *fixedTopology) Edges() [][]*Edge {
+	return t.edges
+}
+
+func (t *fixedTopology) Position(n int) (float64, float64) {
+	return t.x[n], t.y[n]
+}
+
+// newCircleTopology creates a topology of n nodes without edges, placed
+// GridSpacing apart around a circle.
+func newCircleTopology(n int) *fixedTopology {
+	t := &fixedTopology{edges: make([][]*Edge, n), x: make([]float64, n), y: make([]float64, n)}
+	radius := float64(n) * GridSpacing / (2 * math.Pi)
+	for i := 0; i < n; i++ {
+		theta := math.Pi/2 - 2*math.Pi*float64(i)/float64(n)
+		t.x[i], t.y[i] = radius*math.Cos(theta), radius*math.Sin(theta)
+	}
+	return t
+}
+
+// link adds edges between a and b in both directions with the given cost.
+// a and b must not already be linked.
+func (t *fixedTopology) link(a, b int, cost float64) {
+	ab, ba := NewEdge(a, b), NewEdge(b, a)
+	ab.Cost, ba.Cost = cost, cost
+	t.edges[a] = append(t.edges[a], ab)
+	t.edges[b] = append(t.edges[b], ba)
+}
+
+// unlink removes the edges between a and b.
+func (t *fixedTopology) unlink(a, b int) {
+	remove := func(from, to int) {
+		for i, e := range t.edges[from] {
+			if e.EndNodeId == to {
+				t.edges[from] = append(t.edges[from][:i], t.edges[from][i+1:]...)
+				return
+			}
+		}
+	}
+	remove(a, b)
+	remove(b, a)
+}
+
+// linked returns whether there is an edge between a and b.
+func (t *fixedTopology) linked(a, b int) bool {
+	for _, e := range t.edges[a] {
+		if e.EndNodeId == b {
+			return true
+		}
+	}
+	return false
+}
+
+// ErdosRenyi generates G(n, p) graphs of N nodes, in which each pair of nodes
+// is joined with probability P. Nodes are placed around a circle and every
+// edge has a cost of 1.
+type ErdosRenyi struct {
+	N int
+	P float64
+}
+
+// Generate draws a G(n, p) graph.
+func (er ErdosRenyi) Generate(r *rand.Rand) (Topology, error) {
+	if er.N < 2 {
+		return nil, errors.New("erdos-renyi graph must have at least 2 nodes")
+	}
+	if er.P < 0 || er.P > 1 {
+		return nil, fmt.Errorf("edge probability must be between 0 and 1, got %v", er.P)
+	}
+	t := newCircleTopology(er.N)
+	for a := 0; a < er.N; a++ {
+		for b := a + 1; b < er.N; b++ {
+			if r.Float64() < er.P {
+				t.link(a, b, 1)
+			}
+		}
+	}
+	return t, nil
+}
+
+// BarabasiAlbert generates preferential attachment graphs of N nodes. The
+// graph starts as a clique of M+1 nodes, and each node added after them is
+// joined to M distinct earlier nodes chosen in proportion to the number of
+// edges they have, so the graph is always connected. Nodes are placed around
+// a circle and every edge has a cost of 1.
+type BarabasiAlbert struct {
+	N, M int
+}
+
+// Generate draws a preferential attachment graph.
+func (ba BarabasiAlbert) Generate(r *rand.Rand) (Topology, error) {
+	if ba.M < 1 || ba.N <= ba.M {
+		return nil, fmt.Errorf("preferential attachment graph must have more than m >= 1 nodes, got %v nodes and m %v", ba.N, ba.M)
+	}
+	t := newCircleTopology(ba.N)
+	// each node appears once for every edge it has, so picking uniformly
+	// from ends picks nodes in proportion to their degree
+	var ends []int
+	for a := 0; a <= ba.M; a++ {
+		for b := a + 1; b <= ba.M; b++ {
+			t.link(a, b, 1)
+			ends = append(ends, a, b)
+		}
+	}
+	for n := ba.M + 1; n < ba.N; n++ {
+		var targets []int
+		for len(targets) < ba.M {
+			if target := ends[r.Intn(len(ends))]; !t.linked(n, target) {
+				t.link(n, target, 1)
+				targets = append(targets, target)
+			}
+		}
+		for _, target := range targets {
+			ends = append(ends, n, target)
+		}
+	}
+	return t, nil
+}
+
+// WattsStrogatz generates small-world graphs of N nodes. The graph starts as
+// a ring with each node joined to its K nearest neighbors, K/2 on each side,
+// and each edge is then rewired with probability Beta to join its first node
+// to a node chosen uniformly at random. Nodes are placed around a circle and
+// every edge has a cost of 1.
+type WattsStrogatz struct {
+	N, K int
+	Beta float64
+}
+
+// Generate draws a small-world graph.
+func (ws WattsStrogatz) Generate(r *rand.Rand) (Topology, error) {
+	if ws.K < 2 || ws.K%2 != 0 || ws.K >= ws.N {
+		return nil, fmt.Errorf("small-world graph must have an even k of at least 2 and less than the number of nodes, got k %v and %v nodes", ws.K, ws.N)
+	}
+	if ws.Beta < 0 || ws.Beta > 1 {
+		return nil, fmt.Errorf("rewiring probability must be between 0 and 1, got %v", ws.Beta)
+	}
+	t := newCircleTopology(ws.N)
+	for a := 0; a < ws.N; a++ {
+		for j := 1; j <= ws.K/2; j++ {
+			t.link(a, (a+j)%ws.N, 1)
+		}
+	}
+	for j := 1; j <= ws.K/2; j++ {
+		for a := 0; a < ws.N; a++ {
+			b := (a + j) % ws.N
+			if r.Float64() >= ws.Beta {
+				continue
+			}
+			// nodes already joined to every other node can't be rewired
+			c := r.Intn(ws.N)
+			for tries := 0; (c == a || t.linked(a, c)) && tries < ws.N; tries++ {
+				c = r.Intn(ws.N)
+			}
+			if c == a || t.linked(a, c) {
+				continue
+			}
+			t.unlink(a, b)
+			t.link(a, c, 1)
+		}
+	}
+	return t, nil
+}
+
+// RandomGeometric generates random geometric graphs of N nodes placed
+// uniformly at random in a square of area N, so there is on average one node
+// per unit of area, with each pair of nodes within Radius of each other
+// joined. Each edge costs the distance between its nodes, and nodes are
+// placed GridSpacing apart for each unit of distance.
+type RandomGeometric struct {
+	N      int
+	Radius float64
+}
+
+// Generate draws a random geometric graph.
+func (rg RandomGeometric) Generate(r *rand.Rand) (Topology, error) {
+	if rg.N < 2 {
+		return nil, errors.New("random geometric graph must have at least 2 nodes")
+	}
+	if rg.Radius <= 0 {
+		return nil, fmt.Errorf("radius must be positive, got %v", rg.Radius)
+	}
+	side := math.Sqrt(float64(rg.N))
+	t := &fixedTopology{edges: make([][]*Edge, rg.N), x: make([]float64, rg.N), y: make([]float64, rg.N)}
+	for n := 0; n < rg.N; n++ {
+		t.x[n], t.y[n] = r.Float64()*side, r.Float64()*side
+	}
+	for a := 0; a < rg.N; a++ {
+		for b := a + 1; b < rg.N; b++ {
+			if d := math.Hypot(t.x[a]-t.x[b], t.y[a]-t.y[b]); d > 0 && d <= rg.Radius {
+				t.link(a, b, d)
+			}
+		}
+	}
+	for n := range t.x {
+		t.x[n], t.y[n] = t.x[n]*GridSpacing, t.y[n]*GridSpacing
+	}
+	return t, nil
+}
@@ -0,0 +1,114 @@
+package acogo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// countEdges returns the number of edges of t, counting each pair of edges
+// between two nodes once.
+func countEdges(t Topology) int {
+	count := 0
+	for a, out := range t.Edges() {
+		for _, e := range out {
+			if a < e.EndNodeId {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// TestGenerators ensures that each generator draws graphs with the expected
+// number of edges, with edges in both directions.
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		gen   Generator
+		edges int
+	}{
+		{ErdosRenyi{10, 0}, 0},
+		{ErdosRenyi{10, 1}, 45},
+		{BarabasiAlbert{20, 3}, 6 + 16*3},
+		{WattsStrogatz{20, 4, 0}, 40},
+		{WattsStrogatz{20, 4, 0.5}, 40},
+		{RandomGeometric{10, 100}, 45},
+	}
+	for _, test := range tests {
+		topology, err := test.gen.Generate(rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := countEdges(topology); n != test.edges {
+			t.Error(fmt.Sprintf("%+v: expected %v edges but got %v", test.gen, test.edges, n))
+		}
+		joined := make(map[[2]int]bool)
+		for _, out := range topology.Edges() {
+			for _, e := range out {
+				joined[[2]int{e.StartNodeId, e.EndNodeId}] = true
+			}
+		}
+		for pair := range joined {
+			if !joined[[2]int{pair[1], pair[0]}] || pair[0] == pair[1] {
+				t.Error(fmt.Sprintf("%+v: expected edges between %v and %v in both directions", test.gen, pair[0], pair[1]))
+			}
+		}
+	}
+
+	// without rewiring, each ws node is joined to its nearest neighbors
+	topology, _ := WattsStrogatz{8, 4, 0}.Generate(rand.New(rand.NewSource(1)))
+	g := NewTopologyGraph(topology, 0, 4, 0.3)
+	validateNode(g.Nodes[0], []int{1, 2, 6, 7}, Home, t)
+}
+
+// TestRandomGeometric ensures that edges of random geometric graphs join
+// nodes within the radius and cost the distance between them.
+func TestRandomGeometric(t *testing.T) {
+	g, err := NewRandomGraph(RandomGeometric{50, 2}, 3, 0, 49, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range g.Nodes {
+		for _, e := range n.OutEdges {
+			end := g.Nodes[e.EndNodeId]
+			d := math.Hypot(end.X-n.X, end.Y-n.Y) / GridSpacing
+			if math.Abs(d-e.Cost) > 1e-9 || e.Cost > 2 {
+				t.Error(fmt.Sprintf("expected edge %v -> %v to cost its length %v, at most 2, but got %v", n.Id, end.Id, d, e.Cost))
+			}
+		}
+	}
+}
+
+// TestNewRandomGraph ensures that NewRandomGraph draws the same graph for
+// the same seed and reports graphs which cannot connect home and goal.
+func TestNewRandomGraph(t *testing.T) {
+	gen := ErdosRenyi{30, 0.15}
+	a, err := NewRandomGraph(gen, 5, 0, 29, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewRandomGraph(gen, 5, 0, 29, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ToJSON(a), ToJSON(b)) {
+		t.Error("expected the same graph for the same seed")
+	}
+	if err := a.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewRandomGraph(ErdosRenyi{10, 0}, 1, 0, 9, 0.3); err == nil {
+		t.Error("expected error for graph without edges")
+	}
+	if _, err := NewRandomGraph(gen, 1, 0, 30, 0.3); err == nil {
+		t.Error("expected error for goal out of range")
+	}
+	for _, gen := range []Generator{ErdosRenyi{10, 2}, BarabasiAlbert{3, 3}, WattsStrogatz{10, 3, 0.1}, RandomGeometric{10, 0}} {
+		if _, err := NewRandomGraph(gen, 1, 0, 1, 0.3); err == nil {
+			t.Error(fmt.Sprintf("expected error for %+v", gen))
+		}
+	}
+}
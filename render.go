@@ -0,0 +1,396 @@
+package acogo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Sizes in pixels of the parts of graphs drawn by WriteSVG and WritePNG.
+const (
+	renderMargin     = 36.0
+	renderNodeRadius = 10.0
+	renderEndRadius  = 14.0 // radius of home and goal nodes
+	renderMinWidth   = 1.0
+	renderMaxWidth   = 6.0
+	renderArrowLen   = 8.0
+	renderEdgeOffset = 3.0 // distance edges are moved aside so a->b and b->a both show
+	renderLegendW    = 96.0
+	renderLegendBox  = 20.0
+)
+
+var (
+	renderBackground = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	renderText       = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+	renderNodeColors = map[NodeType]color.RGBA{
+		Home: {0x8B, 0x00, 0x00, 0xFF}, // maroon
+		Goal: {0x00, 0x80, 0x00, 0xFF}, // green
+		Path: {0xD3, 0xD3, 0xD3, 0xFF}, // light grey
+	}
+	renderWall = color.RGBA{0x40, 0x40, 0x40, 0xFF} // dark grey
+)
+
+// WriteSVG draws g as an SVG image to w, without needing Graphviz. Nodes are
+// drawn at their positions, or around a circle if any node has none. Edges
+// are colored and thickened by their pheromone as set in opts, which is
+// interpreted as it is by ToDot.
+func WriteSVG(w io.Writer, g *Graph, opts DotOptions) error {
+	l := newRenderLayout(g, opts)
+	c := &svgCanvas{}
+	fmt.Fprintf(&c.buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%v\" height=\"%v\" viewBox=\"0 0 %v %v\">\n",
+		l.width, l.height, l.width, l.height)
+	render(c, g, l, opts)
+	c.buf.WriteString("</svg>\n")
+	_, err := w.Write(c.buf.Bytes())
+	return err
+}
+
+// WritePNG draws g as a PNG image to w in the same way as WriteSVG, except
+// that the PNG has no text labels.
+func WritePNG(w io.Writer, g *Graph, opts DotOptions) error {
+	return png.Encode(w, RenderImage(g, opts))
+}
+
+// RenderImage draws g as an image in the same way as WritePNG.
+func RenderImage(g *Graph, opts DotOptions) *image.RGBA {
+	l := newRenderLayout(g, opts)
+	c := &imageCanvas{image.NewRGBA(image.Rect(0, 0, int(math.Ceil(l.width)), int(math.Ceil(l.height))))}
+	render(c, g, l, opts)
+	return c.img
+}
+
+// canvas is something a graph can be drawn on. Coordinates are in pixels
+// from the top left corner.
+type canvas interface {
+	fill(c color.RGBA)
+	line(x1, y1, x2, y2, width float64, c color.RGBA)
+	polygon(points [][2]float64, c color.RGBA)
+	circle(x, y, r float64, c color.RGBA)
+	text(x, y float64, s string, c color.RGBA)
+}
+
+// renderLayout holds the position of each node on the canvas and the size of
+// the canvas.
+type renderLayout struct {
+	pos           [][2]float64
+	width, height float64
+	sc            scale
+}
+
+// newRenderLayout places the nodes of g on a canvas, leaving room for the
+// legend if opts.Legend is set.
+func newRenderLayout(g *Graph, opts DotOptions) renderLayout {
+	positioned := true
+	for _, n := range g.Nodes {
+		positioned = positioned && n.HasPos
+	}
+
+	// node positions in points with y increasing upwards, as in DOT
+	pos := make([][2]float64, len(g.Nodes))
+	if positioned {
+		for i, n := range g.Nodes {
+			pos[i] = [2]float64{n.X, n.Y}
+		}
+	} else {
+		// place the nodes clockwise around a circle, starting at the top
+		r := math.Max(GridSpacing, float64(len(g.Nodes))*GridSpacing/(2*math.Pi))
+		for i := range pos {
+			theta := math.Pi/2 - 2*math.Pi*float64(i)/float64(len(pos))
+			pos[i] = [2]float64{r * math.Cos(theta), r * math.Sin(theta)}
+		}
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range pos {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	if len(pos) == 0 {
+		minX, minY, maxX, maxY = 0, 0, 0, 0
+	}
+
+	// flip y so it increases downwards and move the graph inside the margin
+	l := renderLayout{pos: pos, sc: newScale(g, opts)}
+	for i, p := range pos {
+		l.pos[i] = [2]float64{p[0] - minX + renderMargin, maxY - p[1] + renderMargin}
+	}
+	l.width = maxX - minX + 2*renderMargin
+	l.height = maxY - minY + 2*renderMargin
+	if opts.Legend {
+		l.width += renderLegendW
+		l.height = math.Max(l.height, 2*renderMargin+(legendEntries+1)*renderLegendBox)
+	}
+	return l
+}
+
+// renderEdge is an edge to draw and the color and width to draw it with.
+type renderEdge struct {
+	e     *Edge
+	c     color.RGBA
+	width float64
+	order int
+}
+
+// renderEdges sorts edges into the order they are drawn in.
+type renderEdges []renderEdge
+
+func (r renderEdges) Len() int      { return len(r) }
+func (r renderEdges) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r renderEdges) Less(i, j int) bool {
+	if r[i].order != r[j].order {
+		return r[i].order < r[j].order
+	}
+	return r[i].e.Pheromone() < r[j].e.Pheromone()
+}
+
+// render draws g onto c with the nodes placed by l.
+func render(c canvas, g *Graph, l renderLayout, opts DotOptions) {
+	c.fill(renderBackground)
+
+	// map each highlighted edge to its highlight and the order it is drawn in
+	highlighted := make(map[[2]int]int)
+	for i, h := range opts.Highlights {
+		for j := 1; j < len(h.Path); j++ {
+			highlighted[[2]int{h.Path[j-1], h.Path[j]}] = i
+		}
+	}
+
+	// draw edges with less pheromone first so edges with more are on top,
+	// and highlighted edges over all of them
+	var edges []renderEdge
+	for _, n := range g.Nodes {
+		for _, e := range n.OutEdges {
+			v := l.sc.at(e.Pheromone())
+			re := renderEdge{e, opts.ColorMap.At(v), renderMinWidth + v*(renderMaxWidth-renderMinWidth), -1}
+			if i, ok := highlighted[[2]int{e.StartNodeId, e.EndNodeId}]; ok {
+				h := opts.Highlights[i]
+				re.c, re.width, re.order = parseColor(h.Color), h.Penwidth, i
+			}
+			edges = append(edges, re)
+		}
+	}
+	sort.Stable(renderEdges(edges))
+	for _, re := range edges {
+		renderEdgeTo(c, g, l, re, opts.Labels)
+	}
+
+	for i, n := range g.Nodes {
+		x, y := l.pos[i][0], l.pos[i][1]
+		r := renderNodeRadius
+		if n.Type != Path {
+			r = renderEndRadius
+		}
+		fill := renderNodeColors[n.Type]
+		if n.isWall() {
+			fill = renderWall
+		}
+		c.circle(x, y, r, fill)
+		c.text(x, y, n.Name, renderText)
+	}
+
+	if opts.Legend {
+		x := l.width - renderLegendW + renderMargin/2
+		c.text(x+renderLegendBox/2, renderMargin, "pheromone", renderText)
+		for i := 0; i < legendEntries; i++ {
+			v := float64(i) / (legendEntries - 1)
+			y := renderMargin + float64(i+1)*renderLegendBox
+			c.polygon([][2]float64{{x, y - renderLegendBox/2}, {x + renderLegendBox, y - renderLegendBox/2},
+				{x + renderLegendBox, y + renderLegendBox/2}, {x, y + renderLegendBox/2}}, opts.ColorMap.At(v))
+			c.text(x+2*renderLegendBox, y, strconv.FormatFloat(l.sc.value(v), 'g', 3, 64), renderText)
+		}
+	}
+}
+
+// renderEdgeTo draws an edge as a line from the edge of its start node to an
+// arrowhead at the edge of its end node, moved aside so that the edge back
+// the other way does not cover it.
+func renderEdgeTo(c canvas, g *Graph, l renderLayout, re renderEdge, label bool) {
+	start, end := l.pos[re.e.StartNodeId], l.pos[re.e.EndNodeId]
+	dx, dy := end[0]-start[0], end[1]-start[1]
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	ux, uy := dx/length, dy/length
+	nx, ny := -uy*renderEdgeOffset, ux*renderEdgeOffset
+
+	startR, endR := renderNodeRadius, renderNodeRadius
+	if g.Nodes[re.e.StartNodeId].Type != Path {
+		startR = renderEndRadius
+	}
+	if g.Nodes[re.e.EndNodeId].Type != Path {
+		endR = renderEndRadius
+	}
+
+	x1, y1 := start[0]+ux*startR+nx, start[1]+uy*startR+ny
+	tipX, tipY := end[0]-ux*endR+nx, end[1]-uy*endR+ny
+	baseX, baseY := tipX-ux*renderArrowLen, tipY-uy*renderArrowLen
+	half := re.width/2 + renderArrowLen/3
+
+	c.line(x1, y1, baseX, baseY, re.width, re.c)
+	c.polygon([][2]float64{{tipX, tipY}, {baseX - uy*half, baseY + ux*half}, {baseX + uy*half, baseY - ux*half}}, re.c)
+	if label {
+		c.text((x1+tipX)/2+3*nx, (y1+tipY)/2+3*ny, strconv.FormatFloat(re.e.Pheromone(), 'g', 3, 64), renderText)
+	}
+}
+
+// parseColor parses a "#RRGGBB" or "#RRGGBBAA" color, returning black if it
+// is malformed.
+func parseColor(s string) color.RGBA {
+	var c color.RGBA
+	c.A = 0xFF
+	switch len(s) {
+	case 7:
+		fmt.Sscanf(s, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 9:
+		fmt.Sscanf(s, "#%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	}
+	return c
+}
+
+// svgCanvas draws SVG elements into a buffer.
+type svgCanvas struct {
+	buf bytes.Buffer
+}
+
+func (s *svgCanvas) fill(c color.RGBA) {
+	fmt.Fprintf(&s.buf, "<rect width=\"100%%\" height=\"100%%\" %v/>\n", svgPaint("fill", c))
+}
+
+func (s *svgCanvas) line(x1, y1, x2, y2, width float64, c color.RGBA) {
+	fmt.Fprintf(&s.buf, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke-width=\"%.1f\" stroke-linecap=\"round\" %v/>\n",
+		x1, y1, x2, y2, width, svgPaint("stroke", c))
+}
+
+func (s *svgCanvas) polygon(points [][2]float64, c color.RGBA) {
+	s.buf.WriteString("<polygon points=\"")
+	for i, p := range points {
+		if i > 0 {
+			s.buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&s.buf, "%.1f,%.1f", p[0], p[1])
+	}
+	fmt.Fprintf(&s.buf, "\" %v/>\n", svgPaint("fill", c))
+}
+
+func (s *svgCanvas) circle(x, y, r float64, c color.RGBA) {
+	fmt.Fprintf(&s.buf, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"%.1f\" %v/>\n", x, y, r, svgPaint("fill", c))
+}
+
+func (s *svgCanvas) text(x, y float64, text string, c color.RGBA) {
+	fmt.Fprintf(&s.buf, "<text x=\"%.1f\" y=\"%.1f\" font-family=\"sans-serif\" font-size=\"10\" text-anchor=\"middle\" dominant-baseline=\"central\" %v>",
+		x, y, svgPaint("fill", c))
+	xml.EscapeText(&s.buf, []byte(text))
+	s.buf.WriteString("</text>\n")
+}
+
+// svgPaint formats c as the SVG fill or stroke attribute named by attr and
+// its opacity.
+func svgPaint(attr string, c color.RGBA) string {
+	paint := fmt.Sprintf("%v=\"#%02X%02X%02X\"", attr, c.R, c.G, c.B)
+	if c.A != 0xFF {
+		paint += fmt.Sprintf(" %v-opacity=\"%.3g\"", attr, float64(c.A)/0xFF)
+	}
+	return paint
+}
+
+// imageCanvas draws antialiased shapes onto an image. It does not draw text.
+type imageCanvas struct {
+	img *image.RGBA
+}
+
+func (m *imageCanvas) fill(c color.RGBA) {
+	b := m.img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			m.img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+func (m *imageCanvas) line(x1, y1, x2, y2, width float64, c color.RGBA) {
+	half := width / 2
+	m.shade(math.Min(x1, x2)-half, math.Min(y1, y2)-half, math.Max(x1, x2)+half, math.Max(y1, y2)+half, c,
+		func(x, y float64) float64 {
+			return half - segmentDistance(x, y, x1, y1, x2, y2)
+		})
+}
+
+func (m *imageCanvas) polygon(points [][2]float64, c color.RGBA) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	area := 0.0
+	for i, p := range points {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+		q := points[(i+1)%len(points)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	// the polygon is convex, so a point's depth inside it is its least
+	// distance to the line through any side
+	m.shade(minX, minY, maxX, maxY, c, func(x, y float64) float64 {
+		depth := math.Inf(1)
+		for i, p := range points {
+			q := points[(i+1)%len(points)]
+			length := math.Hypot(q[0]-p[0], q[1]-p[1])
+			if length == 0 {
+				continue
+			}
+			d := ((q[0]-p[0])*(y-p[1]) - (q[1]-p[1])*(x-p[0])) / length
+			if area < 0 {
+				d = -d
+			}
+			depth = math.Min(depth, d)
+		}
+		return depth
+	})
+}
+
+func (m *imageCanvas) circle(x, y, r float64, c color.RGBA) {
+	m.shade(x-r, y-r, x+r, y+r, c, func(px, py float64) float64 {
+		return r - math.Hypot(px-x, py-y)
+	})
+}
+
+func (m *imageCanvas) text(x, y float64, s string, c color.RGBA) {}
+
+// shade blends c into each pixel in the box from x1, y1 to x2, y2 by how
+// far inside a shape the pixel's center is, where depth returns the
+// distance inside the shape, negative outside it.
+func (m *imageCanvas) shade(x1, y1, x2, y2 float64, c color.RGBA, depth func(x, y float64) float64) {
+	r := image.Rect(int(math.Floor(x1))-1, int(math.Floor(y1))-1, int(math.Ceil(x2))+1, int(math.Ceil(y2))+1).Intersect(m.img.Bounds())
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			coverage := math.Max(0, math.Min(1, depth(float64(px)+0.5, float64(py)+0.5)+0.5))
+			if coverage == 0 {
+				continue
+			}
+			a := coverage * float64(c.A) / 0xFF
+			dst := m.img.RGBAAt(px, py)
+			blend := func(s, d uint8) uint8 {
+				return uint8(math.Floor(float64(s)*a + float64(d)*(1-a) + 0.5))
+			}
+			m.img.SetRGBA(px, py, color.RGBA{blend(c.R, dst.R), blend(c.G, dst.G), blend(c.B, dst.B),
+				uint8(math.Floor(0xFF*a + float64(dst.A)*(1-a) + 0.5))})
+		}
+	}
+}
+
+// segmentDistance returns the distance from x, y to the line segment from
+// x1, y1 to x2, y2.
+func segmentDistance(x, y, x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = math.Max(0, math.Min(1, ((x-x1)*dx+(y-y1)*dy)/lengthSq))
+	}
+	return math.Hypot(x-x1-t*dx, y-y1-t*dy)
+}
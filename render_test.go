@@ -0,0 +1,101 @@
+package acogo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// TestWriteSVG ensures that WriteSVG writes well formed SVG with an element
+// for each node and edge.
+func TestWriteSVG(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	opts := DefaultDotOptions()
+	opts.Legend = false
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, g, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	d := xml.NewDecoder(&buf)
+	counts := make(map[string]int)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if err.Error() != "EOF" {
+				t.Fatal(err)
+			}
+			break
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			counts[start.Name.Local]++
+		}
+	}
+
+	edges := 0
+	for _, n := range g.Nodes {
+		edges += len(n.OutEdges)
+	}
+	if counts["circle"] != len(g.Nodes) || counts["text"] != len(g.Nodes) {
+		t.Error(fmt.Sprintf("expected %v nodes and labels but got %v and %v", len(g.Nodes), counts["circle"], counts["text"]))
+	}
+	if counts["line"] != edges || counts["polygon"] != edges {
+		t.Error(fmt.Sprintf("expected %v edges and arrowheads but got %v and %v", edges, counts["line"], counts["polygon"]))
+	}
+}
+
+// TestWritePNG ensures that WritePNG draws home and goal nodes at their
+// positions in their colors.
+func TestWritePNG(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	var buf bytes.Buffer
+	if err := WritePNG(&buf, g, DefaultDotOptions()); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// home is in the top left corner and goal in the bottom right
+	far := int(renderMargin + 2*GridSpacing)
+	for _, test := range []struct {
+		x, y     int
+		expected color.RGBA
+	}{
+		{int(renderMargin), int(renderMargin), renderNodeColors[Home]},
+		{far, far, renderNodeColors[Goal]},
+		{int(renderMargin), far, renderNodeColors[Path]},
+	} {
+		r, g, b, a := img.At(test.x, test.y).RGBA()
+		c := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		if c != test.expected {
+			t.Error(fmt.Sprintf("expected %v at %v,%v but got %v", test.expected, test.x, test.y, c))
+		}
+	}
+}
+
+// TestRenderCircleLayout ensures that graphs without positions are drawn
+// around a circle.
+func TestRenderCircleLayout(t *testing.T) {
+	g, err := ReadDot([]byte(`digraph { a [role=home]; b; c [role=goal]; a -> b -> c }`), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, g, DefaultDotOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), ">pheromone</text>") {
+		t.Error("expected legend in svg")
+	}
+
+	l := newRenderLayout(g, DotOptions{})
+	if l.pos[0][0] <= l.pos[2][0] || l.pos[0][1] >= l.pos[1][1] {
+		t.Error(fmt.Sprintf("expected nodes clockwise around a circle from the top but got %v", l.pos))
+	}
+}
@@ -0,0 +1,104 @@
+package acogo
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// Snapshotter writes numbered frames of a Graph as it evolves, e.g. from a
+// Colony's OnIteration hook. With the png format it also keeps each frame so
+// that they can be assembled into an animated GIF.
+type Snapshotter struct {
+	// Directory the frames are written to
+	Dir string
+	// Number of iterations between frames
+	Every int
+	// Format of the frames, one of dot, svg or png
+	Format string
+	// Options the frames are drawn with
+	Options DotOptions
+
+	// frames kept for the animated GIF
+	frames []*image.Paletted
+}
+
+// Delays between frames of the animated GIF, in hundredths of a second. The
+// last frame is held for longer so the final state can be seen.
+const (
+	gifFrameDelay = 10
+	gifLastDelay  = 200
+)
+
+// NewSnapshotter creates a Snapshotter writing a frame in format to dir
+// every iterations.
+func NewSnapshotter(dir string, every int, format string, opts DotOptions) *Snapshotter {
+	return &Snapshotter{
+		Dir:     dir,
+		Every:   every,
+		Format:  format,
+		Options: opts,
+	}
+}
+
+// Snapshot writes a frame of g named after iteration, e.g. frame00010.png, if
+// iteration is a multiple of Every.
+func (s *Snapshotter) Snapshot(g *Graph, iteration int) error {
+	if s.Every <= 0 || iteration%s.Every != 0 {
+		return nil
+	}
+
+	f, err := os.Create(filepath.Join(s.Dir, fmt.Sprintf("frame%05d.%v", iteration, s.Format)))
+	if err != nil {
+		return err
+	}
+	switch s.Format {
+	case "svg":
+		err = WriteSVG(f, g, s.Options)
+	case "png":
+		img := RenderImage(g, s.Options)
+		s.frames = append(s.frames, paletted(img))
+		err = png.Encode(f, img)
+	default:
+		_, err = fmt.Fprint(f, ToDot(g, s.Options).String())
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// WriteGIF assembles the png frames written so far into an animated GIF at
+// path. It does nothing if no png frames have been written.
+func (s *Snapshotter) WriteGIF(path string) error {
+	if len(s.frames) == 0 {
+		return nil
+	}
+	anim := &gif.GIF{Image: s.frames, Delay: make([]int, len(s.frames))}
+	for i := range anim.Delay {
+		anim.Delay[i] = gifFrameDelay
+	}
+	anim.Delay[len(anim.Delay)-1] = gifLastDelay
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = gif.EncodeAll(f, anim)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// paletted converts img to the Plan 9 palette for use as a GIF frame.
+func paletted(img image.Image) *image.Paletted {
+	p := image.NewPaletted(img.Bounds(), palette.Plan9)
+	draw.FloydSteinberg.Draw(p, img.Bounds(), img, image.Point{})
+	return p
+}
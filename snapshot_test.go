@@ -0,0 +1,60 @@
+package acogo
+
+import (
+	"context"
+	"fmt"
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSnapshotter ensures that frames are written every Every iterations and
+// assembled into an animated GIF.
+func TestSnapshotter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acogo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := DefaultConfig()
+	cfg.Iterations = 10
+	g := NewGraph(3, 0, 8, 0.3)
+	colony, err := NewColony(g, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSnapshotter(dir, 3, "png", DefaultDotOptions())
+	colony.OnIteration = func(stats IterationStats) {
+		if err := s.Snapshot(g, stats.Iteration); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := colony.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	frames, _ := filepath.Glob(filepath.Join(dir, "frame*.png"))
+	if len(frames) != 3 || filepath.Base(frames[0]) != "frame00003.png" {
+		t.Error(fmt.Sprintf("expected frames 3, 6 and 9 but got %v", frames))
+	}
+
+	path := filepath.Join(dir, "animation.gif")
+	if err := s.WriteGIF(path); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 3 {
+		t.Error(fmt.Sprintf("expected 3 gif frames but got %v", len(anim.Image)))
+	}
+}
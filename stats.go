@@ -0,0 +1,136 @@
+package acogo
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// IterationStats summarizes the paths ants took and the pheromone on the
+// graph after an iteration.
+type IterationStats struct {
+	// Iteration number, starting at 1
+	Iteration int `json:"iteration"`
+	// Lowest and mean cost of the unlooped paths ants took this iteration
+	BestCost float64 `json:"best_cost"`
+	MeanCost float64 `json:"mean_cost"`
+	// Lowest cost of any unlooped path found so far
+	GlobalBestCost float64 `json:"global_best_cost"`
+	// Number of different unlooped paths ants took this iteration
+	DistinctPaths int `json:"distinct_paths"`
+	// Number of ants which gave up before reaching the goal this iteration
+	FailedAnts int `json:"failed_ants"`
+	// Total pheromone on all edges in the graph after evaporation
+	TotalPheromone float64 `json:"total_pheromone"`
+}
+
+// newIterationStats computes the IterationStats for the ants which reached
+// the goal in an iteration.
+func newIterationStats(c *Colony, ants []Ant, failed int) IterationStats {
+	stats := IterationStats{
+		Iteration:      c.Iteration,
+		GlobalBestCost: c.Best.Cost,
+		FailedAnts:     failed,
+		TotalPheromone: c.Graph.TotalPheromone(),
+	}
+
+	paths := make(map[string]bool, len(ants))
+	total := 0.0
+	for i, ant := range ants {
+		path := ant.Path()
+		cost := c.Graph.PathCost(path)
+		if i == 0 || cost < stats.BestCost {
+			stats.BestCost = cost
+		}
+		total += cost
+		paths[pathKey(path)] = true
+	}
+	if len(ants) > 0 {
+		stats.MeanCost = total / float64(len(ants))
+	}
+	stats.DistinctPaths = len(paths)
+	return stats
+}
+
+// pathKey returns a string uniquely identifying the path.
+func pathKey(path []int) string {
+	ids := make([]string, len(path))
+	for i, id := range path {
+		ids[i] = strconv.Itoa(id)
+	}
+	return strings.Join(ids, ",")
+}
+
+// StatsWriter writes IterationStats to an underlying io.Writer.
+type StatsWriter interface {
+	// WriteStats writes the stats for an iteration.
+	WriteStats(IterationStats) error
+	// Flush writes any buffered stats to the underlying io.Writer.
+	Flush() error
+}
+
+// CSVStatsWriter writes IterationStats as CSV, with a header row before the
+// first iteration.
+type CSVStatsWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVStatsWriter creates a CSVStatsWriter writing to w.
+func NewCSVStatsWriter(w io.Writer) *CSVStatsWriter {
+	return &CSVStatsWriter{w: csv.NewWriter(w)}
+}
+
+// WriteStats writes the stats as a CSV row.
+func (s *CSVStatsWriter) WriteStats(stats IterationStats) error {
+	if !s.header {
+		s.header = true
+		header := []string{"iteration", "best_cost", "mean_cost", "global_best_cost", "distinct_paths", "failed_ants", "total_pheromone"}
+		if err := s.w.Write(header); err != nil {
+			return err
+		}
+	}
+	return s.w.Write([]string{
+		strconv.Itoa(stats.Iteration),
+		formatFloat(stats.BestCost),
+		formatFloat(stats.MeanCost),
+		formatFloat(stats.GlobalBestCost),
+		strconv.Itoa(stats.DistinctPaths),
+		strconv.Itoa(stats.FailedAnts),
+		formatFloat(stats.TotalPheromone),
+	})
+}
+
+// Flush writes any buffered rows to the underlying io.Writer.
+func (s *CSVStatsWriter) Flush() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// JSONStatsWriter writes IterationStats as JSON Lines, one JSON object per
+// iteration.
+type JSONStatsWriter struct {
+	enc *json.Encoder
+}
+
+// NewJSONStatsWriter creates a JSONStatsWriter writing to w.
+func NewJSONStatsWriter(w io.Writer) *JSONStatsWriter {
+	return &JSONStatsWriter{json.NewEncoder(w)}
+}
+
+// WriteStats writes the stats as a line of JSON.
+func (s *JSONStatsWriter) WriteStats(stats IterationStats) error {
+	return s.enc.Encode(stats)
+}
+
+// Flush does nothing as JSONStatsWriter does not buffer.
+func (s *JSONStatsWriter) Flush() error {
+	return nil
+}
+
+// formatFloat formats f with the fewest digits needed to represent it.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
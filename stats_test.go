@@ -0,0 +1,62 @@
+package acogo
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestIterationStats ensures that stats are computed from the paths of the
+// ants in an iteration.
+func TestIterationStats(t *testing.T) {
+	g := NewGraph(3, 0, 8, 0.5)
+	c := &Colony{Graph: g, Iteration: 3}
+	ants := []Ant{pathAnt{0, 4, 8}, pathAnt{0, 1, 2, 5, 8}, pathAnt{0, 4, 8}}
+	c.Best.Update(g, ants[0].Path())
+
+	stats := newIterationStats(c, ants, 2)
+	expected := IterationStats{
+		Iteration:      3,
+		BestCost:       2 * math.Sqrt2,
+		MeanCost:       stats.MeanCost,
+		GlobalBestCost: 2 * math.Sqrt2,
+		DistinctPaths:  2,
+		FailedAnts:     2,
+		TotalPheromone: 40 * InitialPheromone,
+	}
+	if mean := (4*math.Sqrt2 + 4) / 3; math.Abs(stats.MeanCost-mean) > 1e-9 {
+		t.Error(fmt.Sprintf("expected mean cost %v but got %v", mean, stats.MeanCost))
+	}
+	if stats != expected {
+		t.Error(fmt.Sprintf("expected stats %+v but got %+v", expected, stats))
+	}
+}
+
+// TestStatsWriters ensures that stats are written as CSV and JSON Lines.
+func TestStatsWriters(t *testing.T) {
+	stats := IterationStats{1, 2.5, 3, 2.5, 4, 0, 100}
+
+	var buf bytes.Buffer
+	w := NewCSVStatsWriter(&buf)
+	w.WriteStats(stats)
+	stats.Iteration = 2
+	w.WriteStats(stats)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "iteration,best_cost,mean_cost,global_best_cost,distinct_paths,failed_ants,total_pheromone\n" +
+		"1,2.5,3,2.5,4,0,100\n2,2.5,3,2.5,4,0,100\n"
+	if buf.String() != expected {
+		t.Error(fmt.Sprintf("expected CSV %q but got %q", expected, buf.String()))
+	}
+
+	buf.Reset()
+	if err := NewJSONStatsWriter(&buf).WriteStats(stats); err != nil {
+		t.Fatal(err)
+	}
+	expected = `{"iteration":2,"best_cost":2.5,"mean_cost":3,"global_best_cost":2.5,"distinct_paths":4,"failed_ants":0,"total_pheromone":100}` + "\n"
+	if buf.String() != expected {
+		t.Error(fmt.Sprintf("expected JSON %q but got %q", expected, buf.String()))
+	}
+}
@@ -0,0 +1,229 @@
+package acogo
+
+import (
+	"fmt"
+	"math"
+)
+
+// Topology generates the nodes and edges of a graph laid out on a lattice.
+// Nodes are numbered row by row from the top left corner of the lattice.
+type Topology interface {
+	// Edges returns the edges out of each node, with the edges out of node
+	// i at [i]. There is at most one edge from one node to another.
+	Edges() [][]*Edge
+	// Position returns the position of node n in points.
+	Position(n int) (x, y float64)
+}
+
+// NewTopology returns the Topology named by name, one of square, rect,
+// grid4, hex, torus or triangular, with width nodes in each row and height
+// rows. square and rect are both a Grid with diagonal edges. diagonals sets
+// whether nodes of a torus have edges to diagonally adjacent nodes.
+func NewTopology(name string, width, height int, diagonals bool) (Topology, error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("topology must be at least 1x1, got %vx%v", width, height)
+	}
+	switch name {
+	case "square", "rect":
+		return Grid{width, height, true}, nil
+	case "grid4":
+		return Grid{width, height, false}, nil
+	case "hex":
+		return Hexagonal{width, height}, nil
+	case "torus":
+		if width < 3 || height < 3 {
+			return nil, fmt.Errorf("torus must be at least 3x3, got %vx%v", width, height)
+		}
+		return Torus{width, height, diagonals}, nil
+	case "triangular":
+		return Triangular{width, height}, nil
+	}
+	return nil, fmt.Errorf("unknown topology %q", name)
+}
+
+// NewTopologyGraph generates a graph with the nodes and edges of t, placing
+// each node at its position in t.
+func NewTopologyGraph(t Topology, homeNode, goalNode int, decayFactor float64) *Graph {
+	nodes := generateNodes(t.Edges(), homeNode, goalNode)
+	for _, n := range nodes {
+		n.X, n.Y = t.Position(n.Id)
+		n.HasPos = true
+	}
+
+	return &Graph{
+		Nodes:       nodes,
+		HomeIdx:     homeNode,
+		GoalIdx:     goalNode,
+		DecayFactor: decayFactor,
+		Evaporation: SubtractiveEvaporation{decayFactor},
+		TauMin:      MinPheromone,
+	}
+}
+
+// step is the offset from a node to an adjacent node in rows and columns.
+type step struct {
+	rows, cols int
+}
+
+// Steps to adjacent nodes, in the order the nodes are numbered so edges out
+// of nodes not at the edge of a grid are generated in order.
+var (
+	// steps to the nodes above, below, left and right of a node
+	rookSteps = []step{{-1, 0}, {0, -1}, {0, 1}, {1, 0}}
+	// steps to the nodes above, below, left, right and on all four
+	// diagonals of a node
+	kingSteps = []step{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+)
+
+// newStepEdge creates the edge from start to end, which are s apart. Edges
+// between diagonally adjacent nodes have a cost of sqrt(2).
+func newStepEdge(start, end int, s step) *Edge {
+	if s.rows != 0 && s.cols != 0 {
+		return newDiagonalEdge(start, end)
+	}
+	return NewEdge(start, end)
+}
+
+// Grid is a rectangular lattice of Width x Height nodes, each with edges to
+// the adjacent nodes above, below, left and right, and if Diagonals is set,
+// on all four diagonals. Diagonal edges have a cost of sqrt(2).
+type Grid struct {
+	Width, Height int
+	Diagonals     bool
+}
+
+// Edges returns the edges between adjacent nodes of the grid.
+func (gr Grid) Edges() [][]*Edge {
+	steps := rookSteps
+	if gr.Diagonals {
+		steps = kingSteps
+	}
+	edges := make([][]*Edge, gr.Width*gr.Height)
+	for n := range edges {
+		row, col := n/gr.Width, n%gr.Width
+		edges[n] = make([]*Edge, 0, len(steps))
+		for _, s := range steps {
+			r, c := row+s.rows, col+s.cols
+			if r >= 0 && r < gr.Height && c >= 0 && c < gr.Width {
+				edges[n] = append(edges[n], newStepEdge(n, r*gr.Width+c, s))
+			}
+		}
+	}
+	return edges
+}
+
+// Position places nodes GridSpacing apart, with node 0 in the top left
+// corner.
+func (gr Grid) Position(n int) (float64, float64) {
+	return float64(n%gr.Width) * GridSpacing, float64(gr.Height-1-n/gr.Width) * GridSpacing
+}
+
+// Torus is a Grid whose edges wrap around, so nodes in the first and last
+// columns are adjacent, as are nodes in the first and last rows.
+type Torus struct {
+	Width, Height int
+	Diagonals     bool
+}
+
+// Edges returns the edges between adjacent nodes of the torus.
+func (t Torus) Edges() [][]*Edge {
+	steps := rookSteps
+	if t.Diagonals {
+		steps = kingSteps
+	}
+	edges := make([][]*Edge, t.Width*t.Height)
+	for n := range edges {
+		row, col := n/t.Width, n%t.Width
+		edges[n] = make([]*Edge, 0, len(steps))
+		for _, s := range steps {
+			r, c := (row+s.rows+t.Height)%t.Height, (col+s.cols+t.Width)%t.Width
+			edges[n] = append(edges[n], newStepEdge(n, r*t.Width+c, s))
+		}
+	}
+	return edges
+}
+
+// Position places nodes as Grid does.
+func (t Torus) Position(n int) (float64, float64) {
+	return Grid{t.Width, t.Height, t.Diagonals}.Position(n)
+}
+
+// Hexagonal is a honeycomb lattice of Height zigzag rows of Width nodes, in
+// which each node has edges to the nodes on either side of it in its row and
+// to one node in the row above or below. Every edge has a cost of 1.
+type Hexagonal struct {
+	Width, Height int
+}
+
+// Edges returns the edges between adjacent nodes of the honeycomb.
+func (h Hexagonal) Edges() [][]*Edge {
+	edges := make([][]*Edge, h.Width*h.Height)
+	for n := range edges {
+		row, col := n/h.Width, n%h.Width
+		// nodes alternate between the top and bottom of each zigzag row,
+		// with nodes at the bottom joined to the row below
+		vertical := step{-1, 0}
+		if (row+col)%2 == 1 {
+			vertical = step{1, 0}
+		}
+		for _, s := range []step{{0, -1}, {0, 1}, vertical} {
+			r, c := row+s.rows, col+s.cols
+			if r >= 0 && r < h.Height && c >= 0 && c < h.Width {
+				edges[n] = append(edges[n], NewEdge(n, r*h.Width+c))
+			}
+		}
+	}
+	return edges
+}
+
+// Position places nodes at the corners of regular hexagons with sides
+// GridSpacing long.
+func (h Hexagonal) Position(n int) (float64, float64) {
+	row, col := n/h.Width, n%h.Width
+	y := float64(h.Height-1-row) * 1.5
+	if (row+col)%2 == 0 {
+		y += 0.5
+	}
+	return float64(col) * math.Sqrt(3) / 2 * GridSpacing, y * GridSpacing
+}
+
+// Triangular is a triangular lattice of Height rows of Width nodes, with
+// every other row offset by half the distance between nodes. Each node has
+// edges to the nodes on either side of it in its row and to the two nearest
+// nodes in the rows above and below. Every edge has a cost of 1.
+type Triangular struct {
+	Width, Height int
+}
+
+// Edges returns the edges between adjacent nodes of the lattice.
+func (t Triangular) Edges() [][]*Edge {
+	edges := make([][]*Edge, t.Width*t.Height)
+	for n := range edges {
+		row, col := n/t.Width, n%t.Width
+		// odd rows are offset to the right, so their neighbors in the rows
+		// above and below are one column further right
+		offset := -1
+		if row%2 == 1 {
+			offset = 0
+		}
+		steps := []step{{-1, offset}, {-1, offset + 1}, {0, -1}, {0, 1}, {1, offset}, {1, offset + 1}}
+		for _, s := range steps {
+			r, c := row+s.rows, col+s.cols
+			if r >= 0 && r < t.Height && c >= 0 && c < t.Width {
+				edges[n] = append(edges[n], NewEdge(n, r*t.Width+c))
+			}
+		}
+	}
+	return edges
+}
+
+// Position places nodes GridSpacing apart, at the corners of equilateral
+// triangles.
+func (t Triangular) Position(n int) (float64, float64) {
+	row, col := n/t.Width, n%t.Width
+	x := float64(col)
+	if row%2 == 1 {
+		x += 0.5
+	}
+	return x * GridSpacing, float64(t.Height-1-row) * math.Sqrt(3) / 2 * GridSpacing
+}
@@ -0,0 +1,136 @@
+package acogo
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestTopologies ensures that each topology gives nodes edges to the
+// expected neighbors.
+func TestTopologies(t *testing.T) {
+	tests := []struct {
+		name      string
+		topology  Topology
+		neighbors map[int][]int
+	}{
+		{"rect", Grid{4, 3, true}, map[int][]int{
+			0: {1, 4, 5}, 5: {0, 1, 2, 4, 6, 8, 9, 10}, 11: {6, 7, 10},
+		}},
+		{"grid4", Grid{4, 3, false}, map[int][]int{
+			0: {1, 4}, 5: {1, 4, 6, 9}, 11: {7, 10},
+		}},
+		{"torus", Torus{4, 3, false}, map[int][]int{
+			0: {1, 3, 4, 8}, 5: {1, 4, 6, 9}, 11: {3, 7, 8, 10},
+		}},
+		{"torus diagonals", Torus{4, 3, true}, map[int][]int{
+			0: {1, 3, 4, 5, 7, 8, 9, 11},
+		}},
+		// rows zigzag, with nodes 1, 3, 4 and 6 joined to the row below
+		{"hex", Hexagonal{4, 3}, map[int][]int{
+			0: {1}, 1: {0, 2, 5}, 4: {5, 8}, 5: {1, 4, 6}, 9: {8, 10},
+		}},
+		// odd rows are offset to the right
+		{"triangular", Triangular{4, 3}, map[int][]int{
+			0: {1, 4}, 4: {0, 1, 5, 8, 9}, 5: {1, 2, 4, 6, 9, 10}, 10: {5, 6, 9, 11},
+		}},
+	}
+	for _, test := range tests {
+		g := NewTopologyGraph(test.topology, 0, 11, 0.3)
+		if len(g.Nodes) != 12 {
+			t.Fatal(fmt.Sprintf("%v: expected 12 nodes but got %v", test.name, len(g.Nodes)))
+		}
+		for n, neighbors := range test.neighbors {
+			nodeType := Path
+			if n == 0 {
+				nodeType = Home
+			} else if n == 11 {
+				nodeType = Goal
+			}
+			validateNode(g.Nodes[n], neighbors, nodeType, t)
+		}
+		if err := g.Validate(); err != nil {
+			t.Error(fmt.Sprintf("%v: %v", test.name, err))
+		}
+	}
+}
+
+// TestTopologyPositions ensures that edges of hexagonal and triangular
+// lattices are all the same length and that grid diagonals cost sqrt(2).
+func TestTopologyPositions(t *testing.T) {
+	for _, topology := range []Topology{Hexagonal{5, 4}, Triangular{5, 4}} {
+		g := NewTopologyGraph(topology, 0, 19, 0.3)
+		for _, n := range g.Nodes {
+			for _, e := range n.OutEdges {
+				end := g.Nodes[e.EndNodeId]
+				if d := math.Hypot(end.X-n.X, end.Y-n.Y); math.Abs(d-GridSpacing) > 1e-9 || e.Cost != 1 {
+					t.Error(fmt.Sprintf("%T: expected edge %v -> %v of length %v and cost 1 but got %v, %v", topology, n.Id, end.Id, GridSpacing, d, e.Cost))
+				}
+			}
+		}
+	}
+
+	g := NewTopologyGraph(Torus{3, 3, true}, 0, 8, 0.3)
+	if c := g.Nodes[8].InEdge(0).Cost; c != math.Sqrt2 {
+		t.Error(fmt.Sprintf("expected wrapped diagonal 0 -> 8 to cost sqrt(2) but got %v", c))
+	}
+	if x, y := (Grid{4, 3, true}).Position(5); x != GridSpacing || y != GridSpacing {
+		t.Error(fmt.Sprintf("expected node 5 at %v,%v but got %v,%v", GridSpacing, GridSpacing, x, y))
+	}
+}
+
+// TestNewTopology ensures that NewTopology rejects unknown and undersized
+// topologies.
+func TestNewTopology(t *testing.T) {
+	for _, name := range []string{"square", "rect", "grid4", "hex", "torus", "triangular"} {
+		if _, err := NewTopology(name, 4, 3, true); err != nil {
+			t.Error(fmt.Sprintf("%v: %v", name, err))
+		}
+	}
+	if _, err := NewTopology("cube", 4, 3, true); err == nil {
+		t.Error("expected error for unknown topology")
+	}
+	if _, err := NewTopology("rect", 0, 3, true); err == nil {
+		t.Error("expected error for empty rect")
+	}
+	if _, err := NewTopology("torus", 2, 3, true); err == nil {
+		t.Error("expected error for torus too small to wrap around")
+	}
+}
+
+// TestEdgeOrder ensures that nodes have out edges ordered by the node they
+// lead to and in edges ordered by the node they come from, whatever order
+// the topology gives them in.
+func TestEdgeOrder(t *testing.T) {
+	for _, topology := range []Topology{Grid{5, 4, true}, Torus{3, 3, true}, Hexagonal{5, 4}, Triangular{5, 4}} {
+		g := NewTopologyGraph(topology, 0, 1, 0.3)
+		edges := 0
+		for _, n := range g.Nodes {
+			for i, e := range n.OutEdges {
+				if e.StartNodeId != n.Id || i > 0 && e.EndNodeId <= n.OutEdges[i-1].EndNodeId {
+					t.Error(fmt.Sprintf("%+v: out edges of node %v are out of order", topology, n.Id))
+				}
+			}
+			for i, e := range n.InEdges {
+				if e.EndNodeId != n.Id || i > 0 && e.StartNodeId <= n.InEdges[i-1].StartNodeId {
+					t.Error(fmt.Sprintf("%+v: in edges of node %v are out of order", topology, n.Id))
+				}
+			}
+			edges += len(n.OutEdges) - len(n.InEdges)
+		}
+		if edges != 0 {
+			t.Error(fmt.Sprintf("%+v: expected as many in edges as out edges", topology))
+		}
+	}
+}
+
+func benchmarkTopologyGraph(topology Topology, b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NewTopologyGraph(topology, 0, 1, 0.3)
+	}
+}
+
+func BenchmarkGrid100(b *testing.B)    { benchmarkTopologyGraph(Grid{100, 100, true}, b) }
+func BenchmarkGrid1000(b *testing.B)   { benchmarkTopologyGraph(Grid{1000, 1000, true}, b) }
+func BenchmarkGrid4_1000(b *testing.B) { benchmarkTopologyGraph(Grid{1000, 1000, false}, b) }
+func BenchmarkHex1000(b *testing.B)    { benchmarkTopologyGraph(Hexagonal{1000, 1000}, b) }